@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sourceplane/thin/internal/manifest"
+)
+
+// expandAliases replaces a leading alias in args with its expansion,
+// appending any remaining args. Expansions may themselves start with an
+// alias; a chain that revisits an alias is reported as recursive.
+func expandAliases(args []string, aliases map[string]string) ([]string, error) {
+	if len(args) == 0 || len(aliases) == 0 {
+		return args, nil
+	}
+
+	var chain []string
+	seen := map[string]bool{}
+
+	for len(args) > 0 {
+		name := args[0]
+		expansion, ok := aliases[name]
+		if !ok || isBuiltinCommand(name) {
+			break
+		}
+		chain = append(chain, name)
+		if seen[name] {
+			return nil, fmt.Errorf("recursive alias: %s", strings.Join(chain, " -> "))
+		}
+		seen[name] = true
+
+		expanded, err := manifest.SplitArgs(expansion)
+		if err != nil {
+			return nil, fmt.Errorf("alias '%s': %w", name, err)
+		}
+		if len(expanded) == 0 {
+			return nil, fmt.Errorf("alias '%s' has an empty expansion", name)
+		}
+		args = append(expanded, args[1:]...)
+	}
+
+	return args, nil
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandAliases(t *testing.T) {
+	// A long chain is fine as long as no alias repeats
+	chain := map[string]string{"a0": "lite-ci plan"}
+	for i := 1; i < 50; i++ {
+		chain[fmt.Sprintf("a%d", i)] = fmt.Sprintf("a%d", i-1)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		aliases map[string]string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "no alias",
+			args:    []string{"lite-ci", "plan"},
+			aliases: map[string]string{"p": "lite-ci plan"},
+			want:    []string{"lite-ci", "plan"},
+		},
+		{
+			name:    "arguments appended",
+			args:    []string{"p", "--env", "prod"},
+			aliases: map[string]string{"p": `lite-ci plan --message "two words"`},
+			want:    []string{"lite-ci", "plan", "--message", "two words", "--env", "prod"},
+		},
+		{
+			name:    "chained",
+			args:    []string{"dp"},
+			aliases: map[string]string{"dp": "d --prod", "d": "lite-ci deploy"},
+			want:    []string{"lite-ci", "deploy", "--prod"},
+		},
+		{
+			name:    "long chain",
+			args:    []string{"a49", "--dry-run"},
+			aliases: chain,
+			want:    []string{"lite-ci", "plan", "--dry-run"},
+		},
+		{
+			name:    "only the first word expands",
+			args:    []string{"lite-ci", "p"},
+			aliases: map[string]string{"p": "plan"},
+			want:    []string{"lite-ci", "p"},
+		},
+		{
+			name:    "builtin commands aren't expanded",
+			args:    []string{"provider", "list"},
+			aliases: map[string]string{"provider": "lite-ci"},
+			want:    []string{"provider", "list"},
+		},
+		{
+			name:    "expands to a builtin",
+			args:    []string{"pl"},
+			aliases: map[string]string{"pl": "provider list", "provider": "lite-ci"},
+			want:    []string{"provider", "list"},
+		},
+		{
+			name:    "self recursion",
+			args:    []string{"p"},
+			aliases: map[string]string{"p": "p --verbose"},
+			wantErr: "recursive alias: p -> p",
+		},
+		{
+			name:    "mutual recursion",
+			args:    []string{"a"},
+			aliases: map[string]string{"a": "b", "b": "c x", "c": "a"},
+			wantErr: "recursive alias: a -> b -> c -> a",
+		},
+		{
+			name:    "empty expansion",
+			args:    []string{"e"},
+			aliases: map[string]string{"e": "  "},
+			wantErr: "alias 'e' has an empty expansion",
+		},
+		{
+			name:    "unterminated quote",
+			args:    []string{"q"},
+			aliases: map[string]string{"q": `lite-ci plan "oops`},
+			wantErr: "alias 'q':",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandAliases(tt.args, tt.aliases)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Errorf("expandAliases(%q) = %q, %v, want error %q", tt.args, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandAliases(%q) error: %v", tt.args, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandAliases(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
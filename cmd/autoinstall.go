@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sourceplane/thin/internal/runtime"
+	"golang.org/x/term"
+)
+
+// ensureProviderInstalled installs providerRef on first use when it isn't
+// installed yet and its source is known, following the auto-install policy.
+// A different provider installed under the same name is an error.
+func ensureProviderInstalled(ctx context.Context, providerRef *runtime.ProviderRef) error {
+	source, ok := runtime.FindProviderSource(providerRef, config)
+	installed, err := runtime.InstalledProvider(providerRef, source)
+	if err != nil || installed {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("provider %s is not installed and its registry is unknown; "+
+			"run it as <registry>/%s, add it to sources in config, or install it with 'thin provider install'",
+			providerRef, providerRef)
+	}
+	if _, err := runtime.ParseImageReference(source.ImageRef); err != nil {
+		return err
+	}
+
+	if err := checkShadowing(providerRef, source); err != nil {
+		return err
+	}
+
+	policy, err := autoInstallPolicy(source)
+	if err != nil {
+		return err
+	}
+	switch policy {
+	case runtime.AutoInstallNever:
+		return fmt.Errorf("provider %s is not installed and auto-install is disabled; install it with 'thin provider install %s %s'",
+			providerRef, providerRef.Name, source.ImageRef)
+	case runtime.AutoInstallPrompt:
+		if !isInteractive() && !source.Trusted() {
+			return fmt.Errorf("provider %s is not installed; it is mapped to %s by project config, which is only installed from after asking. "+
+				"Add it to sources in user config or install it with 'thin provider install %s %s'",
+				providerRef, source.ImageRef, providerRef.Name, source.ImageRef)
+		}
+		if !isInteractive() {
+			return fmt.Errorf("provider %s is not installed; set THIN_AUTO_INSTALL=always to install it from %s without asking",
+				providerRef, source.ImageRef)
+		}
+		if !confirm(fmt.Sprintf("Provider %s is not installed. Install it from %s?", providerRef, source.ImageRef)) {
+			return fmt.Errorf("provider %s is not installed", providerRef)
+		}
+	}
+
+	// The provider's own output owns stdout
+	runtime.SetMessageOutput(os.Stderr)
+	if err := runtime.ConfigureProgress(progressFlag, quietFlag); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+	if err := runtime.PullProviderOCI(ctx, source.ImageRef, providerRef.Name); err != nil {
+		return fmt.Errorf("failed to install provider: %w", err)
+	}
+	if _, err := runtime.InstallDependencies(ctx, providerRef.Name); err != nil {
+		return fmt.Errorf("failed to install dependencies: %w", err)
+	}
+	return nil
+}
+
+// autoInstallPolicy returns the auto-install policy for installing from
+// source. Sources from project config always ask: a repository can't opt
+// itself into installing its own images.
+func autoInstallPolicy(source *runtime.ProviderSource) (string, error) {
+	policy, err := config.AutoInstallPolicy()
+	if err == nil && policy == runtime.AutoInstallAlways && !source.Trusted() {
+		policy = runtime.AutoInstallPrompt
+	}
+	return policy, err
+}
+
+// checkShadowing refuses to install providerRef when it would take the place
+// of other installed versions: installs are flat (providers/<name>), and
+// ProviderDir resolves every version of a name to the flat directory
+func checkShadowing(providerRef *runtime.ProviderRef, source *runtime.ProviderSource) error {
+	nested := runtime.NestedInstalls(providerRef.Name)
+	if len(nested) == 0 {
+		return nil
+	}
+	return fmt.Errorf("provider %s is not installed, and installing %s as %s would take the place of %s",
+		providerRef, source.ImageRef, providerRef.Name, strings.Join(nested, ", "))
+}
+
+// isInteractive reports whether a user can answer a prompt
+func isInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// confirm asks a yes/no question on stderr, defaulting to no
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
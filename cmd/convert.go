@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sourceplane/thin/internal/manifest"
+	"github.com/sourceplane/thin/internal/runtime"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertTo    string
+	convertWrite bool
+)
+
+// convertOutput is the result of provider convert
+type convertOutput struct {
+	File       string `json:"file" yaml:"file"`
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+	Written    bool   `json:"written" yaml:"written"` // Rewritten in place by --write
+	Manifest   string `json:"manifest" yaml:"manifest"`
+}
+
+var providerConvertCmd = &cobra.Command{
+	Use:   "convert [path]",
+	Short: "Convert a provider manifest to another apiVersion",
+	Long: `Rewrite thin.provider.yaml for another manifest apiVersion. path is the
+manifest file or the package directory containing it (default: current
+directory). The converted manifest is printed unless --write is given.
+
+Converting to an older apiVersion fails if the manifest uses features it
+cannot express, such as per-capability entrypoints in thin.io/v1. Comments
+and key order are not preserved.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "."
+		if len(args) == 1 {
+			path = args[0]
+		}
+		if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+			path = filepath.Join(path, "thin.provider.yaml")
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read manifest: %w", err)
+		}
+
+		converted, err := manifest.Convert(data, convertTo)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s: %w", path, err)
+		}
+
+		out := &convertOutput{File: path, APIVersion: convertTo, Written: convertWrite, Manifest: string(converted)}
+		if convertWrite {
+			if err := os.WriteFile(path, converted, 0644); err != nil {
+				return err
+			}
+			runtime.Statusf("✓ Converted %s to %s", path, convertTo)
+		}
+		return printOutput(cmd, out, func(w io.Writer) {
+			if !convertWrite {
+				w.Write(converted)
+			}
+		})
+	},
+}
+
+func init() {
+	providerConvertCmd.Flags().StringVar(&convertTo, "to", manifest.V2, "target apiVersion")
+	providerConvertCmd.Flags().BoolVarP(&convertWrite, "write", "w", false, "rewrite the manifest in place")
+	providerCmd.AddCommand(providerConvertCmd)
+}
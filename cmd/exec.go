@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sourceplane/thin/internal/runtime"
+	"github.com/spf13/cobra"
+)
+
+var (
+	execProvider string
+	execDir      string
+	execEnv      []string
+	execTimeout  time.Duration
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] -- <capability> [args...]",
+	Short: "Run a provider capability or tool",
+	Long: `Run a capability or tool of a provider.
+
+Without --provider the command is routed to the active provider that defines
+it. --provider accepts a provider reference ([registry/]namespace/name@version)
+or the name of an installed provider. Everything after -- is passed to the
+provider unchanged.
+
+The shorthand forms are equivalent:
+  thin <namespace>/<name>@<version> <capability> [args...]
+  thin <provider-name> <capability> [args...]
+  thin <capability> [args...]
+
+Example:
+  thin exec -- build
+  thin exec --provider acme/ci@v1 --cwd ./service --env LOG_LEVEL=debug --timeout 5m -- plan`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireConfig(); err != nil {
+			return err
+		}
+		opts, err := execOptions()
+		if err != nil {
+			return err
+		}
+		ctx := runtime.WithExecOptions(cmd.Context(), opts)
+		if dryRun {
+			return explainExec(ctx, cmd, args)
+		}
+
+		if execProvider == "" {
+			route, err := runtime.RouteCommand(args[0])
+			if err != nil {
+				return err
+			}
+			return executeRoute(ctx, route, args)
+		}
+
+		providerRef, err := runtime.ParseProviderRef(execProvider)
+		if err != nil {
+			providerRef, err = resolveProviderByName(execProvider)
+			if err != nil {
+				return err
+			}
+		}
+		return executeProviderCommand(ctx, providerRef, args)
+	},
+}
+
+// execOptions validates the exec flags
+func execOptions() (runtime.ExecOptions, error) {
+	opts := runtime.ExecOptions{Timeout: execTimeout}
+
+	for _, kv := range execEnv {
+		if name, _, ok := strings.Cut(kv, "="); !ok || name == "" {
+			return opts, withCode(codeInvalidArgument, fmt.Errorf("invalid --env %q (expected NAME=value)", kv))
+		}
+		opts.Env = append(opts.Env, kv)
+	}
+
+	if execDir != "" {
+		dir, err := filepath.Abs(execDir)
+		if err != nil {
+			return opts, withCode(codeInvalidArgument, err)
+		}
+		if stat, err := os.Stat(dir); err != nil || !stat.IsDir() {
+			return opts, withCode(codeInvalidArgument, fmt.Errorf("--cwd is not a directory: %s", execDir))
+		}
+		opts.Dir = dir
+	}
+
+	if execTimeout < 0 {
+		return opts, withCode(codeInvalidArgument, fmt.Errorf("invalid --timeout %s", execTimeout))
+	}
+	return opts, nil
+}
+
+// execArgs builds the exec command line the shorthand forms stand for
+func execArgs(provider string, args []string) []string {
+	execLine := []string{"exec"}
+	if provider != "" {
+		execLine = append(execLine, "--provider", provider)
+	}
+	return append(append(execLine, "--"), args...)
+}
+
+func init() {
+	execCmd.Flags().StringVar(&execProvider, "provider", "", "Provider reference or installed provider name to run")
+	execCmd.Flags().StringVar(&execDir, "cwd", "", "Working directory of the provider process")
+	execCmd.Flags().StringArrayVar(&execEnv, "env", nil, "Set an environment variable (NAME=value, repeatable)")
+	execCmd.Flags().DurationVar(&execTimeout, "timeout", 0, "Stop the provider after this long (e.g. 30s, 5m)")
+	// Arguments after the capability belong to the provider
+	execCmd.Flags().SetInterspersed(false)
+
+	rootCmd.AddCommand(execCmd)
+}
@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sourceplane/thin/internal/manifest"
+	"github.com/sourceplane/thin/internal/runtime"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// dryRun explains how a command line would run instead of running it
+	dryRun bool
+
+	// explainedArgs and expandedArgs are the command line Execute
+	// dispatched, before and after alias expansion
+	explainedArgs []string
+	expandedArgs  []string
+)
+
+// explainOutput describes how thin resolves a command line
+type explainOutput struct {
+	Args         []string          `json:"args" yaml:"args"`
+	Expanded     []string          `json:"expanded,omitempty" yaml:"expanded,omitempty"` // after alias expansion
+	DataHome     string            `json:"dataHome" yaml:"dataHome"`
+	CacheHome    string            `json:"cacheHome" yaml:"cacheHome"`
+	ConfigHome   string            `json:"configHome" yaml:"configHome"`
+	ProjectRoot  string            `json:"projectRoot" yaml:"projectRoot"`
+	ContextFile  string            `json:"contextFile" yaml:"contextFile"`
+	ConfigFiles  []string          `json:"configFiles" yaml:"configFiles"` // config files that exist, in load order
+	Match        string            `json:"match" yaml:"match"`             // reference, name, route, tool, builtin or none
+	Action       string            `json:"action,omitempty" yaml:"action,omitempty"`
+	Provider     string            `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Layout       string            `json:"layout,omitempty" yaml:"layout,omitempty"` // flat or nested
+	ProviderDir  string            `json:"providerDir,omitempty" yaml:"providerDir,omitempty"`
+	Installed    bool              `json:"installed" yaml:"installed"`
+	InstallFrom  string            `json:"installFrom,omitempty" yaml:"installFrom,omitempty"`
+	ManifestPath string            `json:"manifestPath,omitempty" yaml:"manifestPath,omitempty"`
+	Runtime      string            `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	Candidates   []binaryCandidate `json:"candidates,omitempty" yaml:"candidates,omitempty"`
+	Binary       string            `json:"binary,omitempty" yaml:"binary,omitempty"`
+	Argv         []string          `json:"argv,omitempty" yaml:"argv,omitempty"`
+	Env          []string          `json:"env,omitempty" yaml:"env,omitempty"` // variables added to the inherited environment
+	Dir          string            `json:"dir,omitempty" yaml:"dir,omitempty"`
+	Timeout      string            `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	PreHooks     []string          `json:"preHooks,omitempty" yaml:"preHooks,omitempty"`
+	PostHooks    []string          `json:"postHooks,omitempty" yaml:"postHooks,omitempty"`
+	Problem      string            `json:"problem,omitempty" yaml:"problem,omitempty"`
+}
+
+// binaryCandidate is a binary location and whether it exists
+type binaryCandidate struct {
+	Path   string `json:"path" yaml:"path"`
+	Exists bool   `json:"exists" yaml:"exists"`
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <command line>",
+	Short: "Explain how a command line would run, without running it",
+	Long: `Explain how thin resolves a command line without running anything.
+
+Prints the resolved storage locations, the project context file, the alias
+expansion, how the provider was matched and where it is installed, the
+binary candidates, the final argv after template expansion and the
+environment variables thin adds. 'thin --dry-run <command line>' is the same.
+
+Example:
+  thin explain ci plan --env prod
+  thin -o json explain acme/ci@v1 plan`,
+	DisableFlagParsing: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireConfig(); err != nil {
+			return err
+		}
+		// Provider command lines are explained by exec; what is left here
+		// would not run a provider
+		out := newExplainOutput()
+		var err error
+
+		switch {
+		case len(args) == 1 && (args[0] == "-h" || args[0] == "--help"):
+			return cmd.Help()
+		case len(args) == 0:
+			return withCode(codeInvalidArgument, errors.New("explain requires a command line"))
+		case isBuiltinCommand(args[0]):
+			out.Match = "builtin"
+			out.Action = fmt.Sprintf("runs built-in command 'thin %s'", args[0])
+		default:
+			if ref, parseErr := runtime.ParseProviderRef(args[0]); parseErr == nil && len(args) == 1 {
+				out.Match = "reference"
+				out.Provider = ref.String()
+				out.Action = "sets the active provider"
+				break
+			}
+			out.Match = "none"
+			err = withCode(codeNotFound, fmt.Errorf("unknown command %q: not a built-in command, installed provider or command of an active provider", args[0]))
+			out.Problem = err.Error()
+		}
+		return printExplain(cmd, out, err)
+	},
+}
+
+// explainExec explains an exec command line instead of running it
+func explainExec(ctx context.Context, cmd *cobra.Command, args []string) error {
+	out := newExplainOutput()
+	opts := runtime.ExecOptionsFrom(ctx)
+	out.Dir = opts.Dir
+	if opts.Timeout > 0 {
+		out.Timeout = opts.Timeout.String()
+	}
+
+	var providerRef *runtime.ProviderRef
+	if execProvider == "" {
+		out.Match = "route"
+		route, err := runtime.RouteCommand(args[0])
+		if err != nil {
+			out.Problem = err.Error()
+			return printExplain(cmd, out, err)
+		}
+		out.Provider = route.Provider.ProviderRef.String()
+		if route.ToolPath != "" {
+			out.Match = "tool"
+			out.Installed = true
+			out.Binary = route.ToolPath
+			out.Argv = append([]string{route.ToolPath}, args[1:]...)
+			out.Env = runtime.ExecEnv(ctx)
+			hc := &runtime.HookContext{Provider: &route.Provider.ProviderRef, Args: args, Capability: args[0]}
+			out.PreHooks = matchingHooks(config.Hooks.Pre, hc)
+			out.PostHooks = matchingHooks(config.Hooks.Post, hc)
+			return printExplain(cmd, out, nil)
+		}
+		providerRef = &route.Provider.ProviderRef
+	} else {
+		var err error
+		out.Match = "reference"
+		providerRef, err = runtime.ParseProviderRef(execProvider)
+		if err != nil {
+			out.Match = "name"
+			providerRef, err = resolveProviderByName(execProvider)
+			if err != nil {
+				out.Problem = err.Error()
+				return printExplain(cmd, out, err)
+			}
+		}
+		out.Provider = providerRef.String()
+	}
+
+	hc := &runtime.HookContext{Provider: providerRef, Args: args, Capability: args[0]}
+	out.PreHooks = matchingHooks(config.Hooks.Pre, hc)
+	out.PostHooks = matchingHooks(config.Hooks.Post, hc)
+
+	out.ProviderDir = runtime.ProviderDir(providerRef)
+	out.Layout = "nested"
+	if out.ProviderDir == filepath.Join(runtime.DataHome(), "providers", providerRef.Name) {
+		out.Layout = "flat"
+	}
+
+	source, ok := runtime.FindProviderSource(providerRef, config)
+	installed, err := runtime.InstalledProvider(providerRef, source)
+	out.Installed = installed
+	if err != nil {
+		out.Problem = err.Error()
+		return printExplain(cmd, out, err)
+	}
+	if !out.Installed {
+		if !ok {
+			err := fmt.Errorf("provider %s is not installed and its registry is unknown", providerRef)
+			out.Problem = err.Error()
+			return printExplain(cmd, out, withCode(codeNotFound, err))
+		}
+		out.InstallFrom = source.ImageRef
+		if err := checkShadowing(providerRef, source); err != nil {
+			out.Problem = err.Error()
+			return printExplain(cmd, out, err)
+		}
+		policy, _ := autoInstallPolicy(source)
+		out.Action = fmt.Sprintf("installs the provider from %s first (auto-install: %s)", source.ImageRef, policy)
+		return printExplain(cmd, out, nil)
+	}
+	out.ManifestPath = filepath.Join(out.ProviderDir, "thin.provider.yaml")
+
+	inv, err := resolveProviderCommand(ctx, providerRef, args)
+	if inv == nil {
+		out.Problem = err.Error()
+		return printExplain(cmd, out, err)
+	}
+	out.Runtime = runtime.RuntimeNative
+	if inv.WASI {
+		out.Runtime = runtime.RuntimeWASI
+	}
+	for _, path := range inv.Candidates {
+		_, statErr := os.Stat(path)
+		out.Candidates = append(out.Candidates, binaryCandidate{Path: path, Exists: statErr == nil})
+	}
+	out.Binary = inv.Path
+	out.Argv = append([]string{inv.Path}, inv.Args...)
+	out.Env = runtime.ExecEnv(ctx, inv.Env...)
+	if err != nil {
+		out.Problem = err.Error()
+	}
+	return printExplain(cmd, out, err)
+}
+
+// newExplainOutput describes the locations and command line common to
+// every explanation
+func newExplainOutput() *explainOutput {
+	out := &explainOutput{
+		Args:        explainedArgs,
+		DataHome:    runtime.DataHome(),
+		CacheHome:   runtime.CacheHome(),
+		ConfigHome:  runtime.ConfigHome(),
+		ProjectRoot: runtime.ProjectRoot(),
+		ContextFile: runtime.ActiveProviderPath(),
+		ConfigFiles: []string{},
+	}
+	if out.Args == nil {
+		out.Args = []string{}
+	}
+	if manifest.JoinArgs(expandedArgs) != manifest.JoinArgs(explainedArgs) {
+		out.Expanded = expandedArgs
+	}
+	for _, path := range append([]string{runtime.UserConfigPath()}, runtime.ProjectConfigPaths()...) {
+		if _, err := os.Stat(path); err == nil {
+			out.ConfigFiles = append(out.ConfigFiles, path)
+		}
+	}
+	return out
+}
+
+// matchingHooks returns the commands of the hooks that apply to hc
+func matchingHooks(hooks []runtime.Hook, hc *runtime.HookContext) []string {
+	var runs []string
+	for _, hook := range hooks {
+		if hook.Matches(hc) {
+			runs = append(runs, hook.Run)
+		}
+	}
+	return runs
+}
+
+// printExplain prints out and returns err as already reported, so the
+// explanation is the only output
+func printExplain(cmd *cobra.Command, out *explainOutput, err error) error {
+	if printErr := printOutput(cmd, out, func(w io.Writer) { printExplanation(w, out) }); printErr != nil {
+		return printErr
+	}
+	if err != nil {
+		return reportedError(errorCode(err), err)
+	}
+	return nil
+}
+
+func printExplanation(w io.Writer, out *explainOutput) {
+	orNone := func(s string) string {
+		if s == "" {
+			return "(none)"
+		}
+		return s
+	}
+
+	fmt.Fprintf(w, "command:      %s\n", manifest.JoinArgs(out.Args))
+	if out.Expanded != nil {
+		fmt.Fprintf(w, "aliased to:   %s\n", manifest.JoinArgs(out.Expanded))
+	}
+	fmt.Fprintf(w, "data home:    %s\n", out.DataHome)
+	fmt.Fprintf(w, "cache home:   %s\n", out.CacheHome)
+	fmt.Fprintf(w, "config home:  %s\n", out.ConfigHome)
+	fmt.Fprintf(w, "project root: %s\n", orNone(out.ProjectRoot))
+	fmt.Fprintf(w, "context file: %s\n", out.ContextFile)
+	for _, path := range out.ConfigFiles {
+		fmt.Fprintf(w, "config file:  %s\n", path)
+	}
+
+	match := out.Match
+	if out.Provider != "" {
+		match += " → " + out.Provider
+	}
+	fmt.Fprintf(w, "match:        %s\n", match)
+	if out.Action != "" {
+		fmt.Fprintf(w, "action:       %s\n", out.Action)
+	}
+	if out.ProviderDir != "" {
+		fmt.Fprintf(w, "provider dir: %s (%s layout)\n", out.ProviderDir, out.Layout)
+	}
+	if out.ManifestPath != "" {
+		fmt.Fprintf(w, "manifest:     %s\n", out.ManifestPath)
+	}
+	if out.Runtime != "" {
+		fmt.Fprintf(w, "runtime:      %s\n", out.Runtime)
+	}
+	if len(out.Candidates) > 0 {
+		fmt.Fprintln(w, "binary candidates:")
+		for _, c := range out.Candidates {
+			mark := "✗"
+			if c.Exists {
+				mark = "✓"
+			}
+			fmt.Fprintf(w, "  %s %s\n", mark, c.Path)
+		}
+	}
+	if out.Argv != nil {
+		fmt.Fprintf(w, "argv:         %s\n", manifest.JoinArgs(out.Argv))
+	}
+	if out.Dir != "" {
+		fmt.Fprintf(w, "working dir:  %s\n", out.Dir)
+	}
+	if out.Timeout != "" {
+		fmt.Fprintf(w, "timeout:      %s\n", out.Timeout)
+	}
+	if len(out.Env) > 0 {
+		fmt.Fprintln(w, "env added:")
+		for _, kv := range out.Env {
+			fmt.Fprintf(w, "  %s\n", kv)
+		}
+	}
+	for _, run := range out.PreHooks {
+		fmt.Fprintf(w, "pre hook:     %s\n", run)
+	}
+	for _, run := range out.PostHooks {
+		fmt.Fprintf(w, "post hook:    %s\n", run)
+	}
+	if out.Problem != "" {
+		fmt.Fprintf(w, "problem:      %s\n", out.Problem)
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "explain how a command would run without running it")
+	rootCmd.AddCommand(explainCmd)
+}
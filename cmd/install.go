@@ -3,32 +3,85 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
+	"path/filepath"
 	"time"
 
 	"github.com/sourceplane/thin/internal/runtime"
 	"github.com/spf13/cobra"
 )
 
+// installOutput is the result of provider install
+type installOutput struct {
+	Name           string                        `json:"name" yaml:"name"`
+	Source         string                        `json:"source" yaml:"source"`
+	ManifestDigest string                        `json:"manifestDigest" yaml:"manifestDigest"`
+	Dir            string                        `json:"dir" yaml:"dir"`
+	Files          int                           `json:"files" yaml:"files"`
+	InstalledAt    time.Time                     `json:"installedAt" yaml:"installedAt"`
+	Dependencies   []runtime.InstalledDependency `json:"dependencies" yaml:"dependencies"`
+}
+
 var providerInstallCmd = &cobra.Command{
 	Use:   "install <name> <image-ref>",
 	Short: "Install a provider from an OCI image",
 	Long: `Install a provider from an OCI registry.
 
+Dependencies declared in the provider manifest are resolved and installed
+alongside it.
+
+The image reference is [registry[:port]/]repository[:tag][@digest]. With a
+digest the install is pinned to that manifest. References without a
+registry are pulled from docker.io.
+
 Example:
-  thin provider install lite ghcr.io/sourceplane/lite-ci:v0.1.2`,
+  thin provider install lite ghcr.io/sourceplane/lite-ci:v0.1.2
+  thin provider install lite ghcr.io/sourceplane/lite-ci@sha256:<digest>`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireConfig(); err != nil {
+			return err
+		}
 		name := args[0]
 		imageRef := args[1]
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		if err := runtime.ValidateProviderName(name); err != nil {
+			return err
+		}
+		if _, err := runtime.ParseImageReference(imageRef); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), 10*time.Minute)
 		defer cancel()
 		if err := runtime.PullProviderOCI(ctx, imageRef, name); err != nil {
-			fmt.Fprintf(cmd.OutOrStderr(), "✗ Failed to install provider: %v\n", err)
+			return withCode(codeInstallFailed, fmt.Errorf("failed to install provider: %w", err))
+		}
+		deps, err := runtime.InstallDependencies(ctx, name)
+		if err != nil {
+			return withCode(codeInstallFailed, fmt.Errorf("failed to install dependencies: %w", err))
+		}
+
+		dir := filepath.Join(runtime.DataHome(), "providers", name)
+		receipt, err := runtime.ReadReceipt(dir)
+		if err != nil {
 			return err
 		}
 
-		return nil
+		out := &installOutput{
+			Name:           name,
+			Source:         receipt.Source,
+			ManifestDigest: receipt.ManifestDigest,
+			Dir:            dir,
+			Files:          len(receipt.Files),
+			InstalledAt:    receipt.InstalledAt,
+			Dependencies:   deps,
+		}
+		if out.Dependencies == nil {
+			out.Dependencies = []runtime.InstalledDependency{}
+		}
+		// The install flow already reported its progress in table mode
+		return printOutput(cmd, out, func(io.Writer) {})
 	},
 }
 
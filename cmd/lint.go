@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sourceplane/thin/internal/manifest"
+	"github.com/sourceplane/thin/internal/runtime"
+	"github.com/sourceplane/thin/schema"
+	"github.com/spf13/cobra"
+)
+
+var providerLintCmd = &cobra.Command{
+	Use:   "lint [path]",
+	Short: "Strictly check a provider manifest before publishing",
+	Long: `Check thin.provider.yaml for unknown keys, invalid input types and
+lifecycle stability values, missing required fields, and platform binaries
+that are not present in the package. path is the manifest file or the
+package directory containing it (default: current directory).
+
+Problems are reported as file:line:column: severity: message and the command
+exits non-zero if any errors are found.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "."
+		if len(args) == 1 {
+			path = args[0]
+		}
+
+		result, err := runtime.LintManifest(path)
+		if err != nil {
+			return err
+		}
+
+		errors := result.Errors()
+		out := &lintOutput{
+			File:     result.File,
+			Errors:   errors,
+			Warnings: len(result.Problems) - errors,
+			Problems: result.Problems,
+		}
+		if out.Problems == nil {
+			out.Problems = []runtime.LintProblem{}
+		}
+
+		if err := printOutput(cmd, out, func(w io.Writer) {
+			for _, p := range result.Problems {
+				fmt.Fprintln(w, result.Format(p))
+			}
+			if errors == 0 {
+				fmt.Fprintf(w, "✓ %s: no errors, %d warning(s)\n", result.File, out.Warnings)
+			}
+		}); err != nil {
+			return err
+		}
+
+		if errors > 0 {
+			err := fmt.Errorf("%s: %d error(s), %d warning(s)", result.File, errors, out.Warnings)
+			if machineOutput() {
+				return reportedError(codeLintFailed, err)
+			}
+			return withCode(codeLintFailed, err)
+		}
+		return nil
+	},
+}
+
+// lintOutput is the result of provider lint
+type lintOutput struct {
+	File     string                `json:"file" yaml:"file"`
+	Errors   int                   `json:"errors" yaml:"errors"`
+	Warnings int                   `json:"warnings" yaml:"warnings"`
+	Problems []runtime.LintProblem `json:"problems" yaml:"problems"`
+}
+
+var schemaAPIVersion string
+
+var providerSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for thin.provider.yaml",
+	Long: fmt.Sprintf(`Print the JSON Schema for thin.provider.yaml. Editors using
+yaml-language-server can reference the published copies with:
+
+  # yaml-language-server: $schema=%s
+  # yaml-language-server: $schema=%s`, schema.ProviderManifestURL, schema.ProviderManifestV2URL),
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, ok := schema.ForAPIVersion(schemaAPIVersion)
+		if !ok {
+			return fmt.Errorf("no schema for apiVersion %s (expected one of: %s)", schemaAPIVersion, strings.Join(manifest.Versions, ", "))
+		}
+		_, err := os.Stdout.Write(data)
+		return err
+	},
+}
+
+func init() {
+	providerSchemaCmd.Flags().StringVar(&schemaAPIVersion, "api-version", manifest.V1, "manifest apiVersion")
+	providerCmd.AddCommand(providerLintCmd)
+	providerCmd.AddCommand(providerSchemaCmd)
+}
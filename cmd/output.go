@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/sourceplane/thin/internal/runtime"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats for --output
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// outputFormat is set by the global --output flag
+var outputFormat = outputTable
+
+// progressFlag and quietFlag are set by the global --progress and --quiet
+// flags
+var (
+	progressFlag string
+	quietFlag    bool
+)
+
+// machineOutput reports whether stdout carries json or yaml documents
+func machineOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// validateOutputFormat checks --output and moves progress messages to stderr
+// when stdout carries machine-readable output
+func validateOutputFormat() error {
+	switch outputFormat {
+	case outputTable:
+	case outputJSON, outputYAML:
+		runtime.SetMessageOutput(os.Stderr)
+	default:
+		return withCode(codeInvalidArgument,
+			fmt.Errorf("invalid output format %q (expected %s, %s or %s)", outputFormat, outputJSON, outputYAML, outputTable))
+	}
+	return nil
+}
+
+// printOutput writes v as a json or yaml document, or calls table to print
+// the human-readable form
+func printOutput(cmd *cobra.Command, v interface{}, table func(w io.Writer)) error {
+	w := cmd.OutOrStdout()
+	switch outputFormat {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(v)
+	case outputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	table(w)
+	return nil
+}
+
+// Error codes reported in machine-readable error output. These are part of
+// the output schema; add new codes rather than renaming existing ones.
+const (
+	codeError            = "error"
+	codeInvalidArgument  = "invalid_argument"
+	codeInvalidReference = "invalid_reference"
+	codeNotFound         = "not_found"
+	codeNoActiveProvider = "no_active_provider"
+	codeAmbiguousCommand = "ambiguous_command"
+	codeIntegrity        = "integrity_mismatch"
+	codeHookFailed       = "hook_failed"
+	codeInstallFailed    = "install_failed"
+	codeVerifyFailed     = "verification_failed"
+	codeLintFailed       = "lint_failed"
+	codeTimeout          = "timeout"
+	codeConnectionFailed = "connection_failed"
+	codeInvalidConfig    = "invalid_config"
+)
+
+// codedError attaches an error code to err. reported errors have already
+// been described by the command's own output and only set the exit status.
+type codedError struct {
+	code     string
+	err      error
+	reported bool
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// withCode attaches an error code to err
+func withCode(code string, err error) error {
+	return &codedError{code: code, err: err}
+}
+
+// reportedError marks err as already described by the command's output
+func reportedError(code string, err error) error {
+	return &codedError{code: code, err: err, reported: true}
+}
+
+// errorCode classifies err for machine-readable output
+func errorCode(err error) string {
+	var coded *codedError
+	var ambiguous *runtime.AmbiguousCommandError
+	var providerTampered *runtime.ProviderTamperedError
+	var assetsTampered *runtime.AssetsTamperedError
+	var hookErr *runtime.HookError
+
+	switch {
+	case errors.As(err, &coded):
+		return coded.code
+	case errors.Is(err, runtime.ErrInvalidProviderRef), errors.Is(err, runtime.ErrInvalidImageReference):
+		return codeInvalidReference
+	case errors.Is(err, runtime.ErrInvalidProviderName):
+		return codeInvalidArgument
+	case errors.Is(err, runtime.ErrNoActiveProvider):
+		return codeNoActiveProvider
+	case errors.As(err, &ambiguous):
+		return codeAmbiguousCommand
+	case errors.As(err, &providerTampered), errors.As(err, &assetsTampered):
+		return codeIntegrity
+	case errors.As(err, &hookErr):
+		return codeHookFailed
+	case errors.Is(err, context.DeadlineExceeded):
+		return codeTimeout
+	case errors.Is(err, fs.ErrNotExist):
+		return codeNotFound
+	}
+	return codeError
+}
+
+// errorOutput is the machine-readable form of a failed command
+type errorOutput struct {
+	Error errorDetail `json:"error" yaml:"error"`
+}
+
+type errorDetail struct {
+	Code    string `json:"code" yaml:"code"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// reportError prints err in the selected output format: as an error
+// document on stdout for json/yaml, or as text on stderr
+func reportError(err error) {
+	var coded *codedError
+	if errors.As(err, &coded) && coded.reported {
+		return
+	}
+
+	if !machineOutput() {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+
+	out := errorOutput{Error: errorDetail{Code: errorCode(err), Message: err.Error()}}
+	if outputFormat == outputYAML {
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		enc.Encode(out)
+		enc.Close()
+		return
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	enc.Encode(out)
+}
+
+// providerOutput describes an installed or active provider
+type providerOutput struct {
+	Ref       string `json:"ref" yaml:"ref"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Name      string `json:"name" yaml:"name"`
+	Version   string `json:"version" yaml:"version"`
+	Active    bool   `json:"active" yaml:"active"`
+	Priority  int    `json:"priority" yaml:"priority"` // Routing priority; 0 when not active
+	Dir       string `json:"dir" yaml:"dir"`
+}
+
+// newProviderOutput describes ref, looking up whether it is active
+func newProviderOutput(ref *runtime.ProviderRef, active []*runtime.ActiveProvider) providerOutput {
+	out := providerOutput{
+		Ref:       ref.String(),
+		Namespace: ref.Namespace,
+		Name:      ref.Name,
+		Version:   ref.Version,
+		Dir:       runtime.ProviderDir(ref),
+	}
+	for _, a := range active {
+		if a.ProviderRef.Matches(ref) {
+			out.Active = true
+			out.Priority = a.Priority
+			break
+		}
+	}
+	return out
+}
+
+// providersOutput is the result of provider list, provider active,
+// provider use and provider unuse
+type providersOutput struct {
+	Providers []providerOutput `json:"providers" yaml:"providers"`
+}
+
+// activeProvidersOutput describes the active providers in priority order
+func activeProvidersOutput() (*providersOutput, error) {
+	active, err := runtime.ReadActiveProviders()
+	if err != nil && !errors.Is(err, runtime.ErrNoActiveProvider) {
+		return nil, err
+	}
+	out := &providersOutput{Providers: []providerOutput{}}
+	for _, a := range active {
+		out.Providers = append(out.Providers, newProviderOutput(&a.ProviderRef, active))
+	}
+	return out, nil
+}
+
+// progressDefault returns the --progress default, THIN_PROGRESS or auto
+func progressDefault() string {
+	if mode := os.Getenv("THIN_PROGRESS"); mode != "" {
+		return mode
+	}
+	return runtime.ProgressAuto
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "output format: table, json or yaml")
+	rootCmd.PersistentFlags().StringVar(&progressFlag, "progress", progressDefault(),
+		"progress display: auto, tty, plain, json or none (default from THIN_PROGRESS)")
+	rootCmd.PersistentFlags().BoolVarP(&quietFlag, "quiet", "q", false, "suppress progress and status messages")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(); err != nil {
+			return err
+		}
+		if err := runtime.ConfigureProgress(progressFlag, quietFlag); err != nil {
+			return withCode(codeInvalidArgument, err)
+		}
+		return nil
+	}
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withCode(codeInvalidArgument, err)
+	})
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/sourceplane/thin/internal/runtime"
+	"github.com/spf13/cobra"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{errors.New("boom"), codeError},
+		{withCode(codeLintFailed, errors.New("2 problems")), codeLintFailed},
+		{fmt.Errorf("install: %w", withCode(codeInstallFailed, fs.ErrNotExist)), codeInstallFailed},
+		{reportedError(codeVerifyFailed, errors.New("mismatch")), codeVerifyFailed},
+		{fmt.Errorf("bad: %w", runtime.ErrInvalidProviderRef), codeInvalidReference},
+		{runtime.ErrInvalidImageReference, codeInvalidReference},
+		{fmt.Errorf("%w: %q", runtime.ErrInvalidProviderName, ".."), codeInvalidArgument},
+		{runtime.ErrNoActiveProvider, codeNoActiveProvider},
+		{&runtime.AmbiguousCommandError{Command: "run"}, codeAmbiguousCommand},
+		{&runtime.ProviderTamperedError{}, codeIntegrity},
+		{fmt.Errorf("run: %w", &runtime.AssetsTamperedError{Provider: "ci"}), codeIntegrity},
+		{&runtime.HookError{Phase: "pre", Err: context.DeadlineExceeded}, codeHookFailed},
+		{fmt.Errorf("pull: %w", context.DeadlineExceeded), codeTimeout},
+		{fmt.Errorf("open: %w", fs.ErrNotExist), codeNotFound},
+	}
+	for _, tt := range tests {
+		if got := errorCode(tt.err); got != tt.want {
+			t.Errorf("errorCode(%T %q) = %q, want %q", tt.err, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestErrorOutputSchema(t *testing.T) {
+	out := errorOutput{Error: errorDetail{Code: codeNotFound, Message: "provider acme/ci@v1 not found"}}
+	data, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"error":{"code":"not_found","message":"provider acme/ci@v1 not found"}}`
+	if string(data) != want {
+		t.Errorf("errorOutput = %s, want %s", data, want)
+	}
+}
+
+func TestPrintOutput(t *testing.T) {
+	out := &providersOutput{Providers: []providerOutput{{
+		Ref: "acme/ci@v1", Namespace: "acme", Name: "ci", Version: "v1", Active: true, Priority: 2, Dir: "/data/providers/ci",
+	}}}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{outputJSON, `{
+  "providers": [
+    {
+      "ref": "acme/ci@v1",
+      "namespace": "acme",
+      "name": "ci",
+      "version": "v1",
+      "active": true,
+      "priority": 2,
+      "dir": "/data/providers/ci"
+    }
+  ]
+}
+`},
+		{outputYAML, `providers:
+  - ref: acme/ci@v1
+    namespace: acme
+    name: ci
+    version: v1
+    active: true
+    priority: 2
+    dir: /data/providers/ci
+`},
+		{outputTable, "acme/ci@v1\n"},
+	}
+	defer func(format string) { outputFormat = format }(outputFormat)
+	for _, tt := range tests {
+		outputFormat = tt.format
+		var buf bytes.Buffer
+		cmd := &cobra.Command{}
+		cmd.SetOut(&buf)
+		err := printOutput(cmd, out, func(w io.Writer) {
+			for _, p := range out.Providers {
+				fmt.Fprintln(w, p.Ref)
+			}
+		})
+		if err != nil {
+			t.Errorf("printOutput(%s) error: %v", tt.format, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("printOutput(%s) =\n%s\nwant\n%s", tt.format, got, tt.want)
+		}
+	}
+}
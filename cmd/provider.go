@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/sourceplane/thin/internal/runtime"
 	"github.com/spf13/cobra"
@@ -18,20 +19,88 @@ var providersCmd = &cobra.Command{
 	Hidden: true, // Hidden alias for provider
 }
 
+var (
+	addActiveProvider bool
+	activePriority    int
+)
+
 var providerUseCmd = &cobra.Command{
 	Use:   "use <namespace>/<name>@<version>",
 	Short: "Set active provider",
+	Long: `Set the active provider for this project.
+
+With --add, the provider is activated alongside the existing active providers.
+Tool and capability names route to the provider that defines them; when
+several do, the one with the highest --priority wins.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ref, err := runtime.ParseProviderRef(args[0])
+		if err != nil {
+			return err
+		}
+		added := addActiveProvider || cmd.Flags().Changed("priority")
+		if added {
+			err = runtime.AddActiveProvider(ref, activePriority)
+		} else {
+			err = runtime.WriteActiveProvider(ref)
+		}
+		if err != nil {
+			return err
+		}
+
+		out, err := activeProvidersOutput()
+		if err != nil {
+			return err
+		}
+		return printOutput(cmd, out, func(w io.Writer) {
+			if added {
+				fmt.Fprintf(w, "Added active provider %s (priority %d)\n", ref, activePriority)
+				return
+			}
+			fmt.Fprintf(w, "Active provider set to %s/%s@%s\n", ref.Namespace, ref.Name, ref.Version)
+		})
+	},
+}
+
+var providerUnuseCmd = &cobra.Command{
+	Use:   "unuse <namespace>/<name>@<version>",
+	Short: "Deactivate a provider",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ref, err := runtime.ParseProviderRef(args[0])
 		if err != nil {
 			return err
 		}
-		if err := runtime.WriteActiveProvider(ref); err != nil {
+		if err := runtime.RemoveActiveProvider(ref); err != nil {
 			return err
 		}
-		fmt.Printf("Active provider set to %s/%s@%s\n", ref.Namespace, ref.Name, ref.Version)
-		return nil
+
+		out, err := activeProvidersOutput()
+		if err != nil {
+			return err
+		}
+		return printOutput(cmd, out, func(w io.Writer) {
+			fmt.Fprintf(w, "Deactivated provider %s\n", ref)
+		})
+	},
+}
+
+var providerActiveCmd = &cobra.Command{
+	Use:   "active",
+	Short: "List active providers in priority order",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := runtime.ReadActiveProviders(); err != nil {
+			return err
+		}
+		out, err := activeProvidersOutput()
+		if err != nil {
+			return err
+		}
+		return printOutput(cmd, out, func(w io.Writer) {
+			for _, p := range out.Providers {
+				fmt.Fprintf(w, "%s (priority %d)\n", p.Ref, p.Priority)
+			}
+		})
 	},
 }
 
@@ -43,30 +112,40 @@ var providerListCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		active, _ := runtime.ReadActiveProviders()
 
-		if len(providers) == 0 {
-			fmt.Println("No providers installed")
-			return nil
+		out := &providersOutput{Providers: []providerOutput{}}
+		for _, p := range providers {
+			out.Providers = append(out.Providers, newProviderOutput(p, active))
 		}
 
-		// Get active provider for comparison
-		active, _ := runtime.ReadActiveProvider()
-
-		for _, p := range providers {
-			marker := "  "
-			if active != nil && active.Namespace == p.Namespace && active.Name == p.Name && active.Version == p.Version {
-				marker = "* "
+		return printOutput(cmd, out, func(w io.Writer) {
+			if len(out.Providers) == 0 {
+				fmt.Fprintln(w, "No providers installed")
+				return
 			}
-			fmt.Printf("%s%s/%s@%s\n", marker, p.Namespace, p.Name, p.Version)
-		}
-		return nil
+			for _, p := range out.Providers {
+				marker := "  "
+				if p.Active {
+					marker = "* "
+				}
+				fmt.Fprintf(w, "%s%s/%s@%s\n", marker, p.Namespace, p.Name, p.Version)
+			}
+		})
 	},
 }
 
 func init() {
+	providerUseCmd.Flags().BoolVar(&addActiveProvider, "add", false, "Activate alongside existing active providers")
+	providerUseCmd.Flags().IntVar(&activePriority, "priority", 0, "Routing priority when several active providers define a command")
+
 	providerCmd.AddCommand(providerUseCmd)
+	providerCmd.AddCommand(providerUnuseCmd)
+	providerCmd.AddCommand(providerActiveCmd)
 	providerCmd.AddCommand(providerListCmd)
 	
 	providersCmd.AddCommand(providerUseCmd)
+	providersCmd.AddCommand(providerUnuseCmd)
+	providersCmd.AddCommand(providerActiveCmd)
 	providersCmd.AddCommand(providerListCmd)
 }
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sourceplane/thin/internal/runtime"
+	"github.com/spf13/cobra"
+)
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Inspect registry connectivity",
+}
+
+// registryTestOutput is the result of registry test
+type registryTestOutput struct {
+	Registries []*runtime.ConnectionCheck `json:"registries" yaml:"registries"`
+}
+
+var registryTestCmd = &cobra.Command{
+	Use:   "test <registry|image-ref>",
+	Short: "Test the connection to a registry",
+	Long: `Test the connection to a registry with the proxy and TLS settings used
+for pulls.
+
+Given an image reference, rewrites are applied and every mirror of its
+registry is tested as well. Each registry's /v2/ endpoint is requested and
+TLS, proxy, DNS and HTTP problems are reported with a hint at the setting
+that fixes them.
+
+Example:
+  thin registry test ghcr.io
+  thin registry test ghcr.io/sourceplane/lite-ci:v0.1.2`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireConfig(); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(cmd.Context(), 2*time.Minute)
+		defer cancel()
+
+		out := &registryTestOutput{}
+		failed := 0
+		for _, host := range runtime.RegistryHosts(args[0]) {
+			check := runtime.CheckConnection(ctx, host)
+			if !check.OK {
+				failed++
+			}
+			out.Registries = append(out.Registries, check)
+		}
+
+		if err := printOutput(cmd, out, func(w io.Writer) {
+			for _, check := range out.Registries {
+				printConnectionCheck(w, check)
+			}
+		}); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return reportedError(codeConnectionFailed, errors.New("registry connection failed"))
+		}
+		return nil
+	},
+}
+
+func printConnectionCheck(w io.Writer, check *runtime.ConnectionCheck) {
+	proxy := "none"
+	if check.Proxy != "" {
+		proxy = check.Proxy
+	}
+	if !check.OK {
+		fmt.Fprintf(w, "✗ %s: %s\n", check.Registry, check.Problem)
+		fmt.Fprintf(w, "    url: %s (proxy: %s)\n", check.URL, proxy)
+		if check.Hint != "" {
+			fmt.Fprintf(w, "    hint: %s\n", check.Hint)
+		}
+		return
+	}
+
+	fmt.Fprintf(w, "✓ %s: HTTP %d in %dms", check.Registry, check.Status, check.LatencyMs)
+	if check.AuthRequired {
+		fmt.Fprint(w, " (authentication required)")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "    url: %s (proxy: %s)\n", check.URL, proxy)
+	if t := check.TLS; t != nil {
+		verified := "verified"
+		if !t.Verified {
+			verified = "not verified (insecure)"
+		}
+		fmt.Fprintf(w, "    tls: %s, %s, issued by %s, expires %s\n",
+			t.Version, verified, t.Issuer, t.NotAfter.Format("2006-01-02"))
+	}
+}
+
+func init() {
+	registryCmd.AddCommand(registryTestCmd)
+	rootCmd.AddCommand(registryCmd)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sourceplane/thin/internal/runtime"
+	"github.com/spf13/cobra"
+)
+
+// removeOutput is the result of provider remove
+type removeOutput struct {
+	Name string `json:"name" yaml:"name"`
+	Dir  string `json:"dir" yaml:"dir"`
+}
+
+var providerRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"uninstall"},
+	Short:   "Remove an installed provider",
+	Long: `Remove a provider installed with thin provider install.
+
+If another thin process is installing the same provider, remove waits for it
+to finish. Active provider references are left unchanged.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := runtime.RemoveProvider(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		return printOutput(cmd, &removeOutput{Name: args[0], Dir: dir}, func(w io.Writer) {
+			fmt.Fprintf(w, "Removed provider %s from %s\n", args[0], dir)
+		})
+	},
+}
+
+func init() {
+	providerCmd.AddCommand(providerRemoveCmd)
+}
@@ -1,26 +1,48 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	gruntime "runtime"
 	"strings"
-	"text/template"
 
+	"github.com/sourceplane/thin/internal/manifest"
 	"github.com/sourceplane/thin/internal/runtime"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var version = "dev"
 
-// TemplateContext holds variables available for template substitution in manifest
-type TemplateContext struct {
-	ProviderHome string // Root directory of the provider
-	ProviderName string // Name of the provider
-	ProviderVersion string // Version of the provider
-	OS string // Current operating system
-	Arch string // Current architecture
+// config is the merged user and project configuration, loaded by Execute
+var config = &runtime.Config{}
+
+// configErr is why config failed to load. Only commands that need config
+// report it (see requireConfig), so that help, --version and lint keep
+// working with a broken config file.
+var configErr error
+
+// requireConfig returns the error config failed to load with, if any
+func requireConfig() error {
+	return configErr
+}
+
+// isBuiltinCommand reports whether name is a command registered with cobra.
+// Built-in commands are never dispatched to providers or shadowed by aliases.
+func isBuiltinCommand(name string) bool {
+	rootCmd.InitDefaultHelpCmd()
+	rootCmd.InitDefaultCompletionCmd()
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
 }
 
 var rootCmd = &cobra.Command{
@@ -35,15 +57,63 @@ Usage: thin <provider> [command] [args...]`,
 
 func Execute() {
 	args := os.Args[1:]
+
+	ctx, shutdownTracing := runtime.InitTracing(context.Background(), version)
+	// Only the argument count is recorded: command lines can carry secrets
+	ctx, span := runtime.StartSpan(ctx, "thin", attribute.Int("thin.args.count", len(args)))
+
+	// SIGINT, SIGTERM and SIGHUP cancel ctx, and are passed on to a
+	// running provider rather than leaving it behind
+	ctx, stopSignals := runtime.NotifySignals(ctx)
+
+	// exit flushes pending spans before terminating the process
+	exit := func(code int) {
+		stopSignals()
+		span.End()
+		shutdownTracing()
+		os.Exit(code)
+	}
+	defer func() {
+		stopSignals()
+		span.End()
+		shutdownTracing()
+	}()
 	
-	// Parse global flags first
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-		
-		// Stop parsing flags at first non-flag argument
-		if len(arg) > 0 && arg[0] != '-' {
-			break
-		}
+	// Parse global flags first, so they also apply to provider commands
+	globalArgs, args := splitGlobalFlags(args)
+
+	// explain is --dry-run for the rest of the command line
+	if len(args) > 0 && args[0] == "explain" {
+		var explainArgs []string
+		explainArgs, args = splitGlobalFlags(args[1:])
+		globalArgs = append(append(globalArgs, "--dry-run"), explainArgs...)
+	}
+	if err := rootCmd.PersistentFlags().Parse(globalArgs); err != nil {
+		reportError(withCode(codeInvalidArgument, err))
+		exit(1)
+	}
+	explainedArgs = args
+
+	if loaded, err := runtime.LoadConfig(); err != nil {
+		configErr = withCode(codeInvalidConfig, err)
+	} else {
+		config = loaded
+		runtime.ConfigureRegistries(config)
+	}
+
+	// Expand user and project aliases before dispatch
+	args, err := expandAliases(args, config.Aliases)
+	if err != nil {
+		reportError(err)
+		exit(1)
+	}
+	expandedArgs = args
+
+	// Anything but a built-in command may be an alias or a provider, which
+	// can't be told apart without config
+	if configErr != nil && len(args) > 0 && !isBuiltinCommand(args[0]) && !strings.HasPrefix(args[0], "-") {
+		reportError(configErr)
+		exit(1)
 	}
 
 	// Check if first remaining arg is a provider reference (namespace/name@version)
@@ -54,156 +124,347 @@ func Execute() {
 			// First arg is a valid provider reference
 			if len(args) > 1 {
 				// Provider ref followed by command/args
-				cmdArgs := args[1:]
-				
-				if err := executeProviderCommand(providerRef, cmdArgs); err != nil {
-					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-					os.Exit(1)
+				args = execArgs(arg, args[1:])
+			} else if !dryRun {
+				// Provider ref alone, treat as `use` command, installing
+				// it first when its source is known
+				if _, ok := runtime.FindProviderSource(providerRef, config); ok {
+					if err := ensureProviderInstalled(ctx, providerRef); err != nil {
+						reportError(err)
+						exit(1)
+					}
 				}
-				return
-			} else {
-				// Provider ref alone, treat as `use` command
 				if err := runtime.WriteActiveProvider(providerRef); err != nil {
-					if err := rootCmd.Execute(); err != nil {
-						os.Exit(1)
+					if err := rootCmd.ExecuteContext(ctx); err != nil {
+						reportError(err)
+						exit(1)
 					}
 					return
 				}
 				// Success, exit
 				return
 			}
+		} else if len(args) > 1 && !isBuiltinCommand(arg) {
+			// Check if first remaining arg is an installed provider name
+			if _, err := resolveProviderByName(arg); err == nil {
+				args = execArgs(arg, args[1:])
+			}
 		}
 	}
 
-	// Check if first remaining arg is a provider name (use active provider)
-	if len(args) > 0 {
-		arg := args[0]
-		// Skip reserved commands - let Cobra handle these
-		if arg != "tools" && arg != "provider" && arg != "providers" && arg != "use" && arg != "help" && arg != "completion" && arg != "version" {
-			// Try to resolve as a provider name
-			providerRef, err := runtime.ParseProviderRef(arg)
-			if err != nil {
-				// Not a valid provider ref, might be a simple name
-				// Try to find it as an installed provider
-				providerRef, err = resolveProviderByName(arg)
-				if err == nil {
-					// Provider found, execute command with remaining args
-					if len(args) > 1 {
-						cmdArgs := args[1:]
-						if err := executeProviderCommand(providerRef, cmdArgs); err != nil {
-							fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-							os.Exit(1)
-						}
-						return
-					}
-				}
-			} else {
-				// Valid provider ref
-				if len(args) > 1 {
-					cmdArgs := args[1:]
-					if err := executeProviderCommand(providerRef, cmdArgs); err != nil {
-						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-						os.Exit(1)
-					}
-					return
-				}
-			}
+	// Route tool and capability names to the active provider that defines them
+	if len(args) > 0 && !isBuiltinCommand(args[0]) && !strings.HasPrefix(args[0], "-") {
+		_, err := runtime.RouteCommand(args[0])
+		var ambiguous *runtime.AmbiguousCommandError
+		if err == nil || errors.As(err, &ambiguous) {
+			args = execArgs("", args)
 		}
 	}
 
+	// A dry run of anything but a provider command explains why not
+	if dryRun && (len(args) == 0 || args[0] != "exec") {
+		// explain parses no flags; the global ones are already applied
+		args = append([]string{"explain"}, args...)
+		globalArgs = nil
+	}
+
 	// Fall through to normal Cobra execution
-	rootCmd.SetArgs(args)
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	rootCmd.SetArgs(append(globalArgs, args...))
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		reportError(err)
+		exit(1)
+	}
+}
+
+// splitGlobalFlags splits thin's own leading persistent flags off args
+func splitGlobalFlags(args []string) (flags, rest []string) {
+	persistent := rootCmd.PersistentFlags()
+	for len(args) > 0 {
+		arg := args[0]
+		var flag *pflag.Flag
+		switch {
+		case strings.HasPrefix(arg, "--") && len(arg) > 2:
+			name, _, _ := strings.Cut(arg[2:], "=")
+			flag = persistent.Lookup(name)
+		case strings.HasPrefix(arg, "-") && len(arg) == 2:
+			flag = persistent.ShorthandLookup(arg[1:])
+		}
+		if flag == nil {
+			break
+		}
+
+		// The value is the next argument unless given with = or optional
+		n := 1
+		if !strings.Contains(arg, "=") && flag.NoOptDefVal == "" && len(args) > 1 {
+			n = 2
+		}
+		flags = append(flags, args[:n]...)
+		args = args[n:]
+	}
+	return flags, args
+}
+
+// executeProviderCommand runs a provider command between the pre and post
+// hooks from config that match it, installing the provider first if needed.
+// A failing pre hook aborts the run.
+func executeProviderCommand(ctx context.Context, providerRef *runtime.ProviderRef, cmdArgs []string) error {
+	return executeWithHooks(ctx, providerRef, cmdArgs, runProviderCommand)
+}
+
+// executeWithHooks installs the provider if needed and calls run between
+// the pre and post hooks that match cmdArgs
+func executeWithHooks(ctx context.Context, providerRef *runtime.ProviderRef, cmdArgs []string,
+	run func(ctx context.Context, providerRef *runtime.ProviderRef, cmdArgs []string) error) error {
+	if err := ensureProviderInstalled(ctx, providerRef); err != nil {
+		return err
+	}
+
+	hc := &runtime.HookContext{Provider: providerRef, Args: cmdArgs}
+	if len(cmdArgs) > 0 {
+		hc.Capability = cmdArgs[0]
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("thin.capability", hc.Capability))
+	}
+
+	if err := runtime.RunHooks(ctx, runtime.HookPre, config.Hooks.Pre, hc); err != nil {
+		return err
+	}
+
+	runErr := run(ctx, providerRef, cmdArgs)
+
+	hc.ExitCode = runtime.ExitCode(runErr)
+	if err := runtime.RunHooks(ctx, runtime.HookPost, config.Hooks.Post, hc); err != nil {
+		if runErr == nil {
+			return err
+		}
+		// The provider's own failure is the one to report
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return runErr
+}
+
+// runProviderCommand reads the provider manifest and executes the entrypoint with command args
+func runProviderCommand(ctx context.Context, providerRef *runtime.ProviderRef, cmdArgs []string) error {
+	inv, err := resolveProviderCommand(ctx, providerRef, cmdArgs)
+	if err != nil {
+		return err
+	}
+	if inv.WASI {
+		return runtime.ExecWASI(ctx, inv.Path, inv.Args, inv.WASIConfig, inv.Env...)
 	}
+	return runtime.ExecTool(ctx, inv.Path, inv.Args, inv.Env...)
+}
+
+// providerInvocation is a provider command resolved down to the process
+// that runs it
+type providerInvocation struct {
+	Dir        string
+	Manifest   *runtime.ProviderManifest
+	WASI       bool
+	WASIConfig runtime.WASIRuntime
+	Path       string   // binary or wasm module
+	Candidates []string // binary locations tried, in order
+	Args       []string // arguments after the binary, templates expanded
+	Env        []string // variables added for the provider
 }
 
-// executeProviderCommand reads the provider manifest and executes the entrypoint with command args
-func executeProviderCommand(providerRef *runtime.ProviderRef, cmdArgs []string) error {
-	providerDir := filepath.Join(
-		runtime.ThinHome(),
-		"providers",
-		providerRef.Name, // Simplified: no namespace in path for now
-	)
+// resolveProviderCommand reads and verifies the provider manifest, expands
+// its templates and finds the binary, without running anything. When only
+// the binary is missing, the invocation is returned along with the error.
+func resolveProviderCommand(ctx context.Context, providerRef *runtime.ProviderRef, cmdArgs []string) (*providerInvocation, error) {
+	providerDir := runtime.ProviderDir(providerRef)
 
 	// Read provider manifest
+	_, manifestSpan := runtime.StartSpan(ctx, "thin.manifest.parse", attribute.String("thin.provider.dir", providerDir))
 	manifest, err := runtime.ReadProviderManifest(providerDir)
+	runtime.EndSpan(manifestSpan, err)
 	if err != nil {
-		return fmt.Errorf("failed to read provider manifest: %w", err)
+		return nil, fmt.Errorf("failed to read provider manifest: %w", err)
 	}
 
 	if manifest == nil {
-		return fmt.Errorf("provider manifest not found")
+		return nil, fmt.Errorf("provider manifest not found")
 	}
 
-	// Get entrypoint configuration
-	entrypoint := manifest.Entrypoint.Executable
-	if entrypoint == "" {
-		entrypoint = "entrypoint"
+	if err := verifyBeforeRun(providerDir, manifest); err != nil {
+		return nil, err
 	}
 
-	// Resolve full path to binary
-	binaryPath := filepath.Join(providerDir, "bin", entrypoint)
-	
-	if _, err := os.Stat(binaryPath); err != nil {
-		// Try alternate location for multi-platform
-		binaryPath, err = runtime.GetPlatformBinaryPath(providerDir)
-		if err != nil {
-			return fmt.Errorf("binary not found: %w", err)
+	// Expose dependency binaries and assets to the provider
+	env, err := runtime.DependencyEnv(manifest)
+	if err != nil {
+		return nil, err
+	}
+	assetsDir := manifest.AssetsDir(providerDir)
+	env = append(env, "THIN_ASSETS_DIR="+assetsDir)
+
+	isWASI := manifest.SelectRuntime(providerDir) == runtime.RuntimeWASI
+
+	// Create template context with provider information
+	tmplCtx := TemplateContext{
+		ProviderHome:    providerDir,
+		ProviderName:    providerRef.Name,
+		ProviderVersion: providerRef.Version,
+		OS:              gruntime.GOOS,
+		Arch:            gruntime.GOARCH,
+		ProjectRoot:     runtime.ProjectRoot(),
+		AssetsDir:       assetsDir,
+		Env:             environMap(runtime.ExecOptionsFrom(ctx).Env...),
+		Vars:            config.Vars,
+		ThinVersion:     version,
+	}
+	if len(cmdArgs) > 0 {
+		if capability, ok := manifest.Capabilities[cmdArgs[0]]; ok {
+			tmplCtx.Capability = cmdArgs[0]
+			tmplCtx.Inputs, err = expandInputDefaults(capability.Inputs, tmplCtx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to process input defaults of %s: %w", cmdArgs[0], err)
+			}
+		}
+	}
+
+	// Capabilities with a dedicated executable run it directly
+	entrypoint := manifest.Entrypoint
+	dedicated := false
+	if !isWASI && tmplCtx.Capability != "" {
+		if ep := manifest.CapabilityEntrypoint(tmplCtx.Capability); ep != nil {
+			entrypoint, dedicated = *ep, true
+			cmdArgs = cmdArgs[1:]
 		}
 	}
 
 	// Build command arguments
 	var finalArgs []string
-	
+
 	// Add default args from manifest if present
-	if manifest.Entrypoint.DefaultArgs != "" {
-		// Create template context with provider information
-		ctx := TemplateContext{
-			ProviderHome: providerDir,
-			ProviderName: providerRef.Name,
-			ProviderVersion: providerRef.Version,
-			OS: gruntime.GOOS,
-			Arch: gruntime.GOARCH,
-		}
-		
-		// Process default args through template
-		processedArgs, err := processTemplate(manifest.Entrypoint.DefaultArgs, ctx)
+	if !entrypoint.DefaultArgs.Empty() {
+		_, templateSpan := runtime.StartSpan(ctx, "thin.template")
+		defaultArgs, err := expandDefaultArgs(entrypoint.DefaultArgs, tmplCtx)
+		runtime.EndSpan(templateSpan, err)
 		if err != nil {
-			return fmt.Errorf("failed to process default args template: %w", err)
+			return nil, fmt.Errorf("failed to process default args template: %w", err)
 		}
-		
-		// Parse processed args (handle quoted strings)
-		defaultArgs := parseArgs(processedArgs)
 		finalArgs = append(finalArgs, defaultArgs...)
 	}
-	
+
 	// Add command arguments
 	finalArgs = append(finalArgs, cmdArgs...)
 
-	// Execute the binary
-	return runtime.ExecTool(binaryPath, finalArgs)
+	inv := &providerInvocation{
+		Dir:      providerDir,
+		Manifest: manifest,
+		WASI:     isWASI,
+		Args:     finalArgs,
+		Env:      env,
+	}
+
+	// Run the module in the embedded WASI runtime
+	if isWASI {
+		wasiConfig := manifest.Runtime.WASI
+		wasiConfig.Env = nil
+		for _, e := range manifest.Runtime.WASI.Env {
+			entry, err := processTemplate(e, tmplCtx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to process env template: %w", err)
+			}
+			wasiConfig.Env = append(wasiConfig.Env, entry)
+		}
+		wasiConfig.Preopens = nil
+		for _, p := range manifest.Runtime.WASI.Preopens {
+			host, err := processTemplate(p.Host, tmplCtx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to process preopen template: %w", err)
+			}
+			p.Host = host
+			wasiConfig.Preopens = append(wasiConfig.Preopens, p)
+		}
+		inv.WASIConfig = wasiConfig
+		inv.Path = manifest.WASIModulePath(providerDir)
+		inv.Candidates = []string{inv.Path}
+		return inv, nil
+	}
+
+	// Resolve full path to binary
+	if dedicated {
+		inv.Candidates = []string{filepath.Join(providerDir, "bin", filepath.FromSlash(entrypoint.Executable))}
+		inv.Path, err = runtime.ResolveExecutable(providerDir, entrypoint.Executable)
+	} else {
+		inv.Candidates = runtime.EntrypointCandidates(providerDir, manifest)
+		inv.Path, err = runtime.ResolveEntrypoint(providerDir, manifest)
+	}
+	return inv, err
 }
 
-// processTemplate evaluates template variables in a string
-func processTemplate(templateStr string, ctx TemplateContext) (string, error) {
-	tmpl, err := template.New("args").Parse(templateStr)
-	if err != nil {
-		return "", fmt.Errorf("invalid template: %w", err)
+// verifyBeforeRun blocks a run when the provider's immutable assets were
+// tampered with or, with strict verification, any of its files were.
+// manifest is nil for providers without one.
+func verifyBeforeRun(providerDir string, manifest *runtime.ProviderManifest) error {
+	if manifest != nil {
+		if err := runtime.VerifyAssets(providerDir, manifest); err != nil {
+			return err
+		}
 	}
-	
-	var result strings.Builder
-	if err := tmpl.Execute(&result, ctx); err != nil {
-		return "", fmt.Errorf("template execution failed: %w", err)
+	if config.StrictVerify() {
+		if err := runtime.VerifyProviderStrict(providerDir); err != nil {
+			return err
+		}
 	}
-	
-	return result.String(), nil
+	return nil
+}
+
+// executeRoute runs a routed command: tool binaries are executed directly,
+// capabilities are passed to the provider entrypoint. Both are verified and
+// run between the matching hooks.
+func executeRoute(ctx context.Context, route *runtime.Route, args []string) error {
+	if route.ToolPath == "" {
+		return executeProviderCommand(ctx, &route.Provider.ProviderRef, args)
+	}
+	return executeWithHooks(ctx, &route.Provider.ProviderRef, args,
+		func(ctx context.Context, providerRef *runtime.ProviderRef, args []string) error {
+			providerDir := runtime.ProviderDir(providerRef)
+			manifest, err := runtime.ReadProviderManifest(providerDir)
+			if err != nil {
+				return fmt.Errorf("failed to read provider manifest: %w", err)
+			}
+			if err := verifyBeforeRun(providerDir, manifest); err != nil {
+				return err
+			}
+			return runtime.ExecTool(ctx, route.ToolPath, args[1:])
+		})
+}
+
+// expandDefaultArgs evaluates templates in default args. A command line is
+// split into words first and each word is expanded on its own, so expanded
+// values (e.g. Windows paths) are never re-split or unescaped; list elements
+// are expanded one by one. Either way each word stays a single argument.
+func expandDefaultArgs(args manifest.Args, ctx TemplateContext) ([]string, error) {
+	words := args.List
+	if !args.IsList() {
+		var err error
+		words, err = manifest.SplitTemplateArgs(args.Line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid defaultArgs %q: %w", args.Line, err)
+		}
+	}
+
+	expanded := make([]string, 0, len(words))
+	for _, word := range words {
+		value, err := processTemplate(word, ctx)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, value)
+	}
+	return expanded, nil
 }
 
 // resolveProviderByName finds a provider by name from installed providers
 func resolveProviderByName(name string) (*runtime.ProviderRef, error) {
+	if err := runtime.ValidateProviderName(name); err != nil {
+		return nil, err
+	}
+
 	// Since we're using flat directory structure (providers/name), check directly
-	providerDir := filepath.Join(runtime.ThinHome(), "providers", name)
+	providerDir := filepath.Join(runtime.DataHome(), "providers", name)
 	
 	// Check if provider directory exists
 	if stat, err := os.Stat(providerDir); err == nil && stat.IsDir() {
@@ -227,41 +488,7 @@ func resolveProviderByName(name string) (*runtime.ProviderRef, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("provider '%s' not found", name)
-}
-
-// parseArgs parses a command line string into individual arguments, handling quoted strings
-func parseArgs(argString string) []string {
-	var args []string
-	var current strings.Builder
-	inQuote := false
-	var quoteChar rune
-
-	for _, ch := range argString {
-		if (ch == '"' || ch == '\'') && !inQuote {
-			// Starting a quoted section
-			inQuote = true
-			quoteChar = ch
-		} else if ch == quoteChar && inQuote {
-			// Ending a quoted section
-			inQuote = false
-		} else if ch == ' ' && !inQuote {
-			// Space outside quotes - separator
-			if current.Len() > 0 {
-				args = append(args, current.String())
-				current.Reset()
-			}
-		} else {
-			// Regular character
-			current.WriteRune(ch)
-		}
-	}
-
-	if current.Len() > 0 {
-		args = append(args, current.String())
-	}
-
-	return args
+	return nil, withCode(codeNotFound, fmt.Errorf("provider '%s' not found", name))
 }
 
 func init() {
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/sourceplane/thin/internal/manifest"
+)
+
+// TemplateContext holds variables available for template substitution in manifest
+// defaultArgs, WASI env entries and preopens, and capability input defaults
+type TemplateContext struct {
+	ProviderHome    string                 // Root directory of the provider
+	ProviderName    string                 // Name of the provider
+	ProviderVersion string                 // Version of the provider
+	OS              string                 // Current operating system
+	Arch            string                 // Current architecture
+	ProjectRoot     string                 // Nearest directory containing .thin or thin.yaml (empty outside a project)
+	AssetsDir       string                 // Provider assets directory (assets.root)
+	Capability      string                 // Capability being invoked (empty if none)
+	Env             map[string]string      // Environment of the thin process with exec --env applied
+	Vars            map[string]interface{} // vars from user and project config
+	Inputs          map[string]interface{} // Input defaults of the invoked capability, expanded
+	ThinVersion     string                 // Version of thin itself
+}
+
+// templateFuncs are the functions available in manifest templates, along
+// with env, which processTemplate binds to TemplateContext.Env. They only
+// read from the environment; nothing can execute commands or touch files.
+var templateFuncs = template.FuncMap{
+	// default returns value unless it is empty, in which case def:
+	// {{ .Vars.region | default "us-east-1" }}
+	"default": func(def, value interface{}) interface{} {
+		if isEmptyValue(value) {
+			return def
+		}
+		return value
+	},
+	// required fails the template with msg if value is empty:
+	// {{ required "set vars.token" .Vars.token }}
+	"required": func(msg string, value interface{}) (interface{}, error) {
+		if isEmptyValue(value) {
+			return nil, errors.New(msg)
+		}
+		return value, nil
+	},
+	// quote shell-quotes value, e.g. for a command line handed to a shell
+	"quote": func(value interface{}) string {
+		return manifest.JoinArgs([]string{fmt.Sprint(value)})
+	},
+	// join concatenates a list with sep: {{ join "," .Vars.regions }}
+	"join": func(sep string, list interface{}) (string, error) {
+		if list == nil {
+			return "", nil
+		}
+		v := reflect.ValueOf(list)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return fmt.Sprint(list), nil
+		}
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(parts, sep), nil
+	},
+	// Path helpers
+	"pathJoin": filepath.Join,
+	"base":     filepath.Base,
+	"dir":      filepath.Dir,
+	"ext":      filepath.Ext,
+	"clean":    filepath.Clean,
+	"isAbs":    filepath.IsAbs,
+	"abs": func(path string) (string, error) {
+		return filepath.Abs(path)
+	},
+	"toSlash":   filepath.ToSlash,
+	"fromSlash": filepath.FromSlash,
+}
+
+// isEmptyValue reports whether v is nil or the zero value of its type, or
+// an empty string, slice or map
+func isEmptyValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
+
+// processTemplate evaluates template variables in a string
+func processTemplate(templateStr string, ctx TemplateContext) (string, error) {
+	if !strings.Contains(templateStr, "{{") {
+		return templateStr, nil
+	}
+
+	tmpl, err := template.New("args").Funcs(templateFuncs).Funcs(template.FuncMap{
+		// env looks up a variable in .Env: {{ env "HOME" }}
+		"env": func(name string) string {
+			return ctx.Env[name]
+		},
+	}).Parse(templateStr)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, ctx); err != nil {
+		return "", fmt.Errorf("template execution failed: %w", err)
+	}
+
+	return result.String(), nil
+}
+
+// environMap returns the process environment as a map, with the NAME=value
+// entries in overrides applied on top
+func environMap(overrides ...string) map[string]string {
+	env := map[string]string{}
+	for _, kv := range append(os.Environ(), overrides...) {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// expandInputDefaults evaluates templates in the string defaults of a
+// capability's inputs; other defaults are passed through unchanged
+func expandInputDefaults(inputs []manifest.Input, ctx TemplateContext) (map[string]interface{}, error) {
+	defaults := map[string]interface{}{}
+	for _, input := range inputs {
+		if input.Default == nil {
+			continue
+		}
+		s, ok := input.Default.(string)
+		if !ok {
+			defaults[input.Name] = input.Default
+			continue
+		}
+		value, err := processTemplate(s, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("input %s: %w", input.Name, err)
+		}
+		defaults[input.Name] = value
+	}
+	return defaults, nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -16,48 +17,90 @@ var toolsCmd = &cobra.Command{
 	Short: "List tools from the active provider",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if allProviders {
-			return listAllProviderTools()
+			return listAllProviderTools(cmd)
 		}
-		return listActiveProviderTools()
+		return listActiveProviderTools(cmd)
 	},
 }
 
-func listActiveProviderTools() error {
-	dir, err := runtime.ActiveProviderToolsDir()
-	if err != nil {
-		return err
-	}
+// providerToolsOutput lists the tools of one provider
+type providerToolsOutput struct {
+	providerOutput `yaml:",inline"`
+	Tools          []string `json:"tools" yaml:"tools"`
+}
 
+// toolsOutput is the result of thin tools
+type toolsOutput struct {
+	Providers []providerToolsOutput `json:"providers" yaml:"providers"`
+}
+
+// readTools lists the tool binaries in a tools directory
+func readTools(dir string) ([]string, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
+	tools := []string{}
 	for _, e := range entries {
 		if !e.IsDir() {
-			fmt.Println(e.Name())
+			tools = append(tools, e.Name())
 		}
 	}
-	return nil
+	return tools, nil
 }
 
-func listAllProviderTools() error {
-	providers, err := runtime.ListProviders()
+func listActiveProviderTools(cmd *cobra.Command) error {
+	providers, err := runtime.ReadActiveProviders()
 	if err != nil {
 		return err
 	}
 
-	if len(providers) == 0 {
-		fmt.Println("No providers installed")
-		return nil
+	out := &toolsOutput{Providers: []providerToolsOutput{}}
+	for _, p := range providers {
+		tools, err := readTools(filepath.Join(runtime.ProviderDir(&p.ProviderRef), "tools"))
+		if err != nil {
+			// A single active provider must have tools; with several, skip those without
+			if len(providers) == 1 {
+				return err
+			}
+			continue
+		}
+		out.Providers = append(out.Providers, providerToolsOutput{
+			providerOutput: newProviderOutput(&p.ProviderRef, providers),
+			Tools:          tools,
+		})
 	}
 
-	// Get active provider for comparison
-	active, _ := runtime.ReadActiveProvider()
+	return printOutput(cmd, out, func(w io.Writer) {
+		// Single active provider: plain list, as before
+		if len(providers) == 1 {
+			for _, tool := range out.Providers[0].Tools {
+				fmt.Fprintln(w, tool)
+			}
+			return
+		}
+
+		for _, p := range out.Providers {
+			fmt.Fprintf(w, "%s (priority %d):\n", p.Ref, p.Priority)
+			for _, tool := range p.Tools {
+				fmt.Fprintf(w, "  %s\n", tool)
+			}
+			fmt.Fprintln(w)
+		}
+	})
+}
+
+func listAllProviderTools(cmd *cobra.Command) error {
+	providers, err := runtime.ListProviders()
+	if err != nil {
+		return err
+	}
+	active, _ := runtime.ReadActiveProviders()
 
+	out := &toolsOutput{Providers: []providerToolsOutput{}}
 	for _, p := range providers {
 		toolsDir := filepath.Join(
-			runtime.ThinHome(),
+			runtime.DataHome(),
 			"providers",
 			p.Namespace,
 			p.Name,
@@ -65,28 +108,38 @@ func listAllProviderTools() error {
 			"tools",
 		)
 
-		entries, err := os.ReadDir(toolsDir)
+		tools, err := readTools(toolsDir)
 		if err != nil {
 			continue
 		}
+		out.Providers = append(out.Providers, providerToolsOutput{
+			providerOutput: newProviderOutput(p, active),
+			Tools:          tools,
+		})
+	}
 
-		marker := ""
-		if active != nil && active.Namespace == p.Namespace && active.Name == p.Name && active.Version == p.Version {
-			marker = " (active)"
+	return printOutput(cmd, out, func(w io.Writer) {
+		if len(providers) == 0 {
+			fmt.Fprintln(w, "No providers installed")
+			return
 		}
 
-		fmt.Printf("%s/%s@%s%s:\n", p.Namespace, p.Name, p.Version, marker)
+		for _, p := range out.Providers {
+			marker := ""
+			if p.Active {
+				marker = " (active)"
+			}
 
-		for _, e := range entries {
-			if !e.IsDir() {
-				fmt.Printf("  %s\n", e.Name())
+			fmt.Fprintf(w, "%s/%s@%s%s:\n", p.Namespace, p.Name, p.Version, marker)
+			for _, tool := range p.Tools {
+				fmt.Fprintf(w, "  %s\n", tool)
 			}
+			fmt.Fprintln(w)
 		}
-		fmt.Println()
-	}
-	return nil
+	})
 }
 
 func init() {
 	toolsCmd.Flags().BoolVarP(&allProviders, "all-providers", "A", false, "List tools from all providers")
+	rootCmd.AddCommand(toolsCmd)
 }
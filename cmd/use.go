@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/sourceplane/thin/internal/runtime"
 	"github.com/spf13/cobra"
@@ -23,8 +24,13 @@ var useCmd = &cobra.Command{
 			if err := runtime.WriteActiveProvider(ref); err != nil {
 				return err
 			}
-			fmt.Printf("Active provider set to %s/%s@%s\n", ref.Namespace, ref.Name, ref.Version)
-			return nil
+			out, err := activeProvidersOutput()
+			if err != nil {
+				return err
+			}
+			return printOutput(cmd, out, func(w io.Writer) {
+				fmt.Fprintf(w, "Active provider set to %s/%s@%s\n", ref.Namespace, ref.Name, ref.Version)
+			})
 		}
 
 		// Tool specified, set provider and execute tool
@@ -40,7 +46,7 @@ var useCmd = &cobra.Command{
 			return err
 		}
 
-		return runtime.ExecTool(toolPath, toolArgs)
+		return runtime.ExecTool(cmd.Context(), toolPath, toolArgs)
 	},
 }
 
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sourceplane/thin/internal/runtime"
+	"github.com/spf13/cobra"
+)
+
+var providerVerifyCmd = &cobra.Command{
+	Use:   "verify [name | <namespace>/<name>@<version>]",
+	Short: "Verify installed providers against their install receipt",
+	Long: `Re-hash the files of an installed provider and compare them with the
+receipt written at install time, reporting added, missing and modified files.
+Without an argument, every installed provider with a receipt is verified.
+
+Set "verify: strict" in thin config (or THIN_VERIFY=strict) to run this check
+before every provider invocation.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var dirs []string
+		if len(args) == 1 {
+			dir, err := verifyTargetDir(args[0])
+			if err != nil {
+				return err
+			}
+			dirs = []string{dir}
+		} else {
+			entries, err := os.ReadDir(filepath.Join(runtime.DataHome(), "providers"))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			for _, e := range entries {
+				if strings.HasPrefix(e.Name(), ".") {
+					continue // staging directory of an install in progress
+				}
+				dir := filepath.Join(runtime.DataHome(), "providers", e.Name())
+				if _, err := runtime.ReadReceipt(dir); err == nil {
+					dirs = append(dirs, dir)
+				}
+			}
+			if len(dirs) == 0 {
+				return printOutput(cmd, &verifyOutput{Providers: []verifyProviderOutput{}}, func(w io.Writer) {
+					fmt.Fprintln(w, "No providers with install receipts")
+				})
+			}
+		}
+
+		out := &verifyOutput{Providers: []verifyProviderOutput{}}
+		failed := 0
+		for _, dir := range dirs {
+			entry := verifyProviderOutput{Name: filepath.Base(dir), Dir: dir}
+			result, err := runtime.VerifyProvider(dir)
+			if err != nil {
+				entry.Error = err.Error()
+				failed++
+			} else {
+				entry.Name = result.Receipt.Name
+				entry.Source = result.Receipt.Source
+				entry.ManifestDigest = result.Receipt.ManifestDigest
+				entry.Files = len(result.Receipt.Files)
+				entry.OK = result.OK()
+				if !entry.OK {
+					entry.Diff = result.Diff
+					failed++
+				}
+			}
+			out.Providers = append(out.Providers, entry)
+		}
+
+		if err := printOutput(cmd, out, func(w io.Writer) {
+			for _, p := range out.Providers {
+				switch {
+				case p.Error != "":
+					fmt.Fprintf(w, "✗ %s: %s\n", p.Name, p.Error)
+				case p.OK:
+					fmt.Fprintf(w, "✓ %s: %d files match %s (%s)\n", p.Name, p.Files, p.Source, shortDigest(p.ManifestDigest))
+				default:
+					fmt.Fprintf(w, "✗ %s: does not match %s\n", p.Name, p.Source)
+					for _, path := range p.Diff.Modified {
+						fmt.Fprintf(w, "  modified: %s\n", path)
+					}
+					for _, path := range p.Diff.Missing {
+						fmt.Fprintf(w, "  missing:  %s\n", path)
+					}
+					for _, path := range p.Diff.Added {
+						fmt.Fprintf(w, "  added:    %s\n", path)
+					}
+				}
+			}
+		}); err != nil {
+			return err
+		}
+
+		if failed > 0 {
+			err := fmt.Errorf("%d provider(s) failed verification", failed)
+			if machineOutput() {
+				return reportedError(codeVerifyFailed, err)
+			}
+			return withCode(codeVerifyFailed, err)
+		}
+		return nil
+	},
+}
+
+// verifyProviderOutput is the verification result of one provider
+type verifyProviderOutput struct {
+	Name           string            `json:"name" yaml:"name"`
+	Dir            string            `json:"dir" yaml:"dir"`
+	Source         string            `json:"source,omitempty" yaml:"source,omitempty"`
+	ManifestDigest string            `json:"manifestDigest,omitempty" yaml:"manifestDigest,omitempty"`
+	Files          int               `json:"files" yaml:"files"`
+	OK             bool              `json:"ok" yaml:"ok"`
+	Diff           *runtime.TreeDiff `json:"diff,omitempty" yaml:"diff,omitempty"`
+	Error          string            `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// verifyOutput is the result of provider verify
+type verifyOutput struct {
+	Providers []verifyProviderOutput `json:"providers" yaml:"providers"`
+}
+
+// verifyTargetDir resolves a provider name or reference to its install directory
+func verifyTargetDir(arg string) (string, error) {
+	if ref, err := runtime.ParseProviderRef(arg); err == nil {
+		return runtime.ProviderDir(ref), nil
+	}
+	if err := runtime.ValidateProviderName(arg); err != nil {
+		return "", err
+	}
+	return filepath.Join(runtime.DataHome(), "providers", arg), nil
+}
+
+// shortDigest abbreviates a digest for display
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
+	}
+	return digest
+}
+
+func init() {
+	providerCmd.AddCommand(providerVerifyCmd)
+}
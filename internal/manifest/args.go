@@ -0,0 +1,156 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitArgs splits a command line into words using POSIX shell quoting:
+// spaces, tabs and newlines separate words, single quotes preserve
+// everything literally, double quotes allow \" \\ \$ and \` escapes, a
+// backslash outside quotes escapes the next character, and adjacent quoted
+// and unquoted segments join into one word. Empty quotes are an empty
+// argument and unbalanced quotes are an error.
+func SplitArgs(line string) ([]string, error) {
+	return splitArgs(line, false)
+}
+
+// SplitTemplateArgs is SplitArgs for a line that has not been template
+// expanded yet: template actions ({{ ... }}) are kept intact, even when they
+// contain spaces or quotes.
+func SplitTemplateArgs(line string) ([]string, error) {
+	return splitArgs(line, true)
+}
+
+func splitArgs(line string, keepActions bool) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	runes := []rune(line)
+
+	// action copies a template action starting at i and returns its length
+	action := func(i int) (int, error) {
+		end := strings.Index(string(runes[i:]), "}}")
+		if end < 0 {
+			return 0, fmt.Errorf("unterminated template action at offset %d", i)
+		}
+		a := string(runes[i:])[:end+2]
+		word.WriteString(a)
+		return len([]rune(a)), nil
+	}
+	isAction := func(i int) bool {
+		return keepActions && runes[i] == '{' && i+1 < len(runes) && runes[i+1] == '{'
+	}
+
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case isAction(i):
+			n, err := action(i)
+			if err != nil {
+				return nil, err
+			}
+			i += n - 1
+			inWord = true
+
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		case ch == '\\':
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("trailing backslash at offset %d", i)
+			}
+			i++
+			// Backslash-newline is a line continuation
+			if runes[i] != '\n' {
+				word.WriteRune(runes[i])
+				inWord = true
+			}
+
+		case ch == '\'':
+			start := i
+			for i++; i < len(runes) && runes[i] != '\''; i++ {
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unbalanced single quote at offset %d", start)
+			}
+			inWord = true
+
+		case ch == '"':
+			start := i
+			closed := false
+			for i++; i < len(runes); i++ {
+				c := runes[i]
+				if c == '"' {
+					closed = true
+					break
+				}
+				if isAction(i) {
+					n, err := action(i)
+					if err != nil {
+						return nil, err
+					}
+					i += n - 1
+					continue
+				}
+				if c == '\\' && i+1 < len(runes) {
+					switch runes[i+1] {
+					case '"', '\\', '$', '`':
+						i++
+						word.WriteRune(runes[i])
+						continue
+					case '\n':
+						i++
+						continue
+					}
+				}
+				word.WriteRune(c)
+			}
+			if !closed {
+				return nil, fmt.Errorf("unbalanced double quote at offset %d", start)
+			}
+			inWord = true
+
+		default:
+			word.WriteRune(ch)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
+
+// JoinArgs renders args as a command line that SplitArgs splits back into
+// the same words
+func JoinArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteArg quotes arg for SplitArgs if it contains anything but plain word
+// characters. Arguments with template actions are always quoted, since the
+// expanded value may contain spaces.
+func quoteArg(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(arg, " \t\n\r'\"\\") && !strings.Contains(arg, "{{") {
+		return arg
+	}
+	if !strings.Contains(arg, "'") {
+		return "'" + arg + "'"
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`")
+	return `"` + r.Replace(arg) + `"`
+}
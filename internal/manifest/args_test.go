@@ -0,0 +1,111 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"blank", " \t\n ", nil},
+		{"words", "plan --env prod", []string{"plan", "--env", "prod"}},
+		{"tabs and newlines", "a\tb\nc\r\nd", []string{"a", "b", "c", "d"}},
+		{"repeated spaces", "  a   b  ", []string{"a", "b"}},
+		{"single quotes", `'a b' 'c\d' '"'`, []string{"a b", `c\d`, `"`}},
+		{"double quotes", `"a b" "c'd"`, []string{"a b", "c'd"}},
+		{"double quote escapes", `"\" \\ \$ \` + "`" + `"`, []string{`" \ $ ` + "`"}},
+		{"other backslash in double quotes", `"C:\temp\x"`, []string{`C:\temp\x`}},
+		{"backslash escape", `a\ b \'c\"`, []string{"a b", `'c"`}},
+		{"escaped backslash", `C:\\temp`, []string{`C:\temp`}},
+		{"line continuation", "a \\\nb", []string{"a", "b"}},
+		{"line continuation in double quotes", "\"a\\\nb\"", []string{"ab"}},
+		{"empty quotes", `'' "" x`, []string{"", "", "x"}},
+		{"adjacent segments", `--label='a b'"c"d`, []string{"--label=a bcd"}},
+		{"unicode", "héllo 'wörld'", []string{"héllo", "wörld"}},
+		{"braces are literal", "{{ .X }}", []string{"{{", ".X", "}}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitArgs(tt.line)
+			if err != nil {
+				t.Fatalf("SplitArgs(%q) error: %v", tt.line, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitArgs(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitArgsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"unbalanced single quote", "a 'b"},
+		{"unbalanced double quote", `a "b`},
+		{"no escapes in single quotes", `'a\'b'`},
+		{"trailing backslash", `a \`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := SplitArgs(tt.line); err == nil {
+				t.Errorf("SplitArgs(%q) = %q, want error", tt.line, got)
+			}
+		})
+	}
+}
+
+func TestSplitTemplateArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"action with spaces", "--config {{ .ProviderHome }}/config", []string{"--config", "{{ .ProviderHome }}/config"}},
+		{"action with quotes", `--token {{ required "set vars.token" .Vars.token }}`, []string{"--token", `{{ required "set vars.token" .Vars.token }}`}},
+		{"action in double quotes", `"{{ .ProviderHome }} dir"`, []string{"{{ .ProviderHome }} dir"}},
+		{"action in single quotes is literal", `'{{ .X }}'`, []string{"{{ .X }}"}},
+		{"backslashes in action", `{{ pathJoin "C:\\a" "b" }}`, []string{`{{ pathJoin "C:\\a" "b" }}`}},
+		{"adjacent actions", "{{ .A }}{{ .B }}", []string{"{{ .A }}{{ .B }}"}},
+		{"plain words", `a\ b 'c d'`, []string{"a b", "c d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitTemplateArgs(tt.line)
+			if err != nil {
+				t.Fatalf("SplitTemplateArgs(%q) error: %v", tt.line, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitTemplateArgs(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+
+	if got, err := SplitTemplateArgs("--config {{ .ProviderHome"); err == nil {
+		t.Errorf("SplitTemplateArgs with unterminated action = %q, want error", got)
+	}
+}
+
+func TestJoinArgsRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"plain", "words"},
+		{"a b", "", "it's", `C:\temp`, `"quoted"`, "$HOME", "tab\there"},
+		{"{{ .X }}"},
+	}
+	for _, args := range tests {
+		line := JoinArgs(args)
+		got, err := SplitArgs(line)
+		if err != nil {
+			t.Fatalf("SplitArgs(JoinArgs(%q)) error: %v", args, err)
+		}
+		if !reflect.DeepEqual(got, args) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", line, got, args)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+// Package manifest decodes versioned thin.provider.yaml documents into a
+// stable internal representation and converts between API versions.
+//
+// Only the wire types in v1.go and v2.go are tied to a document format; the
+// rest of thin works with Provider, so new apiVersions can be added without
+// breaking providers that were published against older ones.
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported apiVersions
+const (
+	V1 = "thin.io/v1"
+	V2 = "thin.io/v2"
+)
+
+// Kind is the only manifest kind thin understands
+const Kind = "Provider"
+
+// Versions lists the supported apiVersions, oldest first
+var Versions = []string{V1, V2}
+
+// Provider is the version-independent form of a provider manifest
+type Provider struct {
+	APIVersion   string // apiVersion the manifest was decoded from
+	Kind         string
+	Metadata     Metadata
+	Distribution Distribution
+	Runtime      Runtime
+	Entrypoint   Entrypoint
+	Platforms    []Platform
+	Layers       map[string]interface{}
+	Capabilities map[string]Capability
+	Assets       Assets
+	Architecture Architecture
+	Models       map[string]interface{}
+	Dependencies []Dependency
+}
+
+// Metadata describes the provider
+type Metadata struct {
+	Name        string
+	Version     string
+	Description string
+	Homepage    string
+	Maintainers []Maintainer
+	License     string
+}
+
+// Maintainer is a person or team responsible for the provider
+type Maintainer struct {
+	Name  string `yaml:"name,omitempty"`
+	Email string `yaml:"email,omitempty"`
+	URL   string `yaml:"url,omitempty"`
+}
+
+// String renders the maintainer as "Name <email>"
+func (m Maintainer) String() string {
+	switch {
+	case m.Email != "" && m.Name != "":
+		return fmt.Sprintf("%s <%s>", m.Name, m.Email)
+	case m.Email != "":
+		return m.Email
+	case m.Name != "":
+		return m.Name
+	}
+	return m.URL
+}
+
+// Distribution says where the provider is published
+type Distribution struct {
+	Type string `yaml:"type"` // "oci"
+	Ref  string `yaml:"ref"`  // "ghcr.io/sourceplane/lite-ci"
+}
+
+// Runtime selects how the provider is executed
+type Runtime struct {
+	Default   string        `yaml:"default,omitempty"`   // "native" (default) or "wasi"
+	Supported []interface{} `yaml:"supported,omitempty"` // Runtime configurations
+	WASI      WASIRuntime   `yaml:"wasi,omitempty"`
+}
+
+// WASIRuntime configures how a wasi provider module is run
+type WASIRuntime struct {
+	Module   string        `yaml:"module,omitempty"`   // Module path relative to the provider dir (default: bin/provider.wasm)
+	Env      []string      `yaml:"env,omitempty"`      // NAME passes a host variable through; NAME=value sets it
+	Preopens []WASIPreopen `yaml:"preopens,omitempty"` // Host directories visible to the module (default: working dir at /, read-only)
+}
+
+// WASIPreopen maps a host directory into the module's filesystem, read-only
+// unless Writable is set
+type WASIPreopen struct {
+	Host     string `yaml:"host"` // Template-expanded; relative paths resolve against the working dir
+	Guest    string `yaml:"guest,omitempty"`
+	Writable bool   `yaml:"writable,omitempty"`
+}
+
+// Entrypoint is the executable a provider (or a single capability) runs
+type Entrypoint struct {
+	Executable  string
+	DefaultArgs Args
+}
+
+// Args are default arguments, written either as one shell-style line or as
+// an explicit list. A line is split into words after template expansion; list
+// elements are expanded individually and always stay one argument each.
+type Args struct {
+	Line string
+	List []string
+}
+
+// IsList reports whether the arguments were given in list form
+func (a Args) IsList() bool {
+	return a.List != nil
+}
+
+// Empty reports whether no default arguments were given
+func (a Args) Empty() bool {
+	return a.Line == "" && len(a.List) == 0
+}
+
+// Platform maps an os/arch pair to a binary inside the package
+type Platform struct {
+	OS     string `yaml:"os"`
+	Arch   string `yaml:"arch"`
+	Binary string `yaml:"binary"`
+}
+
+// Capability is a command the provider offers
+type Capability struct {
+	Description string
+	Lifecycle   Lifecycle
+	Inputs      []Input
+	Outputs     []Output
+	Entrypoint  *Entrypoint // Dedicated executable; nil runs the provider's entrypoint
+}
+
+// Lifecycle tracks a capability's maturity
+type Lifecycle struct {
+	Stability    string `yaml:"stability,omitempty"` // stable, experimental, deprecated
+	IntroducedIn string `yaml:"introducedIn,omitempty"`
+}
+
+// Input is a capability parameter
+type Input struct {
+	Name        string      `yaml:"name"`
+	Type        string      `yaml:"type,omitempty"`
+	Required    bool        `yaml:"required,omitempty"`
+	Default     interface{} `yaml:"default,omitempty"`
+	Description string      `yaml:"description,omitempty"`
+}
+
+// Output is a value a capability produces
+type Output struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type,omitempty"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// Assets describes the provider's bundled, non-executable files
+type Assets struct {
+	Root         string      `yaml:"root,omitempty"`
+	Contains     []string    `yaml:"contains,omitempty"`
+	Immutability interface{} `yaml:"immutability,omitempty"`
+}
+
+// Architecture documents the provider's design
+type Architecture struct {
+	Pattern    string   `yaml:"pattern,omitempty"`
+	Stages     []string `yaml:"stages,omitempty"`
+	Principles []string `yaml:"principles,omitempty"`
+}
+
+// Dependency is another provider that this provider invokes at runtime
+type Dependency struct {
+	Name    string `yaml:"name"`              // Install name, e.g. "secrets"
+	Ref     string `yaml:"ref"`               // OCI repository without tag, e.g. "ghcr.io/sourceplane/secrets"
+	Version string `yaml:"version,omitempty"` // Version constraint, e.g. "^1.2" or ">=1.2.0 <2.0.0"
+}
+
+// document is the in-memory form of one apiVersion's wire format
+type document interface {
+	toProvider() (*Provider, error)
+}
+
+// newDocument returns an empty wire document for apiVersion
+func newDocument(apiVersion string) (document, error) {
+	switch apiVersion {
+	case V1:
+		return &providerV1{}, nil
+	case V2:
+		return &providerV2{}, nil
+	case "":
+		return nil, errors.New("manifest missing required field: apiVersion")
+	}
+	return nil, fmt.Errorf("unsupported apiVersion: %s (expected one of: %s)", apiVersion, strings.Join(Versions, ", "))
+}
+
+// WireType returns the Go type that apiVersion documents decode into, for
+// tools that inspect the raw YAML against it
+func WireType(apiVersion string) (reflect.Type, error) {
+	doc, err := newDocument(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	return reflect.TypeOf(doc).Elem(), nil
+}
+
+// Decode parses a thin.provider.yaml document of any supported apiVersion
+func Decode(data []byte) (*Provider, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if len(node.Content) == 0 {
+		return nil, errors.New("manifest is empty")
+	}
+	return DecodeNode(node.Content[0])
+}
+
+// DecodeNode decodes a parsed manifest mapping. Field type mismatches are
+// returned as a *yaml.TypeError together with the partially decoded provider.
+func DecodeNode(node *yaml.Node) (*Provider, error) {
+	var header struct {
+		APIVersion string `yaml:"apiVersion"`
+	}
+	if err := node.Decode(&header); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	doc, err := newDocument(header.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	decodeErr := node.Decode(doc)
+	var typeErr *yaml.TypeError
+	if decodeErr != nil && !errors.As(decodeErr, &typeErr) {
+		return nil, fmt.Errorf("failed to parse manifest: %w", decodeErr)
+	}
+
+	provider, err := doc.toProvider()
+	if err != nil {
+		return nil, err
+	}
+	if typeErr != nil {
+		return provider, typeErr
+	}
+	return provider, nil
+}
+
+// Encode writes p as an apiVersion document. It fails if p uses features
+// that apiVersion cannot express.
+func Encode(p *Provider, apiVersion string) ([]byte, error) {
+	var doc interface{}
+	var err error
+	switch apiVersion {
+	case V1:
+		doc, err = fromProviderV1(p)
+	case V2:
+		doc, err = fromProviderV2(p)
+	default:
+		_, err = newDocument(apiVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Convert rewrites a manifest document as apiVersion. Comments and key
+// order of the original are not preserved.
+func Convert(data []byte, apiVersion string) ([]byte, error) {
+	p, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(p, apiVersion)
+}
@@ -0,0 +1,188 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// providerV1 is the thin.io/v1 wire format
+// Spec: https://github.com/sourceplane/thin/blob/main/oci/thin.provider.yaml
+type providerV1 struct {
+	APIVersion   string                  `yaml:"apiVersion"`
+	Kind         string                  `yaml:"kind"`
+	Metadata     metadataV1              `yaml:"metadata"`
+	Distribution Distribution            `yaml:"distribution"`
+	Runtime      Runtime                 `yaml:"runtime,omitempty"`
+	Entrypoint   entrypointV1            `yaml:"entrypoint,omitempty"`
+	Platforms    []Platform              `yaml:"platforms,omitempty"`
+	Layers       map[string]interface{}  `yaml:"layers,omitempty"`
+	Capabilities map[string]capabilityV1 `yaml:"capabilities"`
+	Assets       Assets                  `yaml:"assets,omitempty"`
+	Architecture Architecture            `yaml:"architecture,omitempty"`
+	Models       map[string]interface{}  `yaml:"models,omitempty"`
+	Dependencies []Dependency            `yaml:"dependencies,omitempty"`
+}
+
+type metadataV1 struct {
+	Name        string        `yaml:"name"`
+	Version     string        `yaml:"version"`
+	Description string        `yaml:"description,omitempty"`
+	Homepage    string        `yaml:"homepage,omitempty"`
+	Maintainers []interface{} `yaml:"maintainers,omitempty"` // "Name <email>" strings or name/email/url maps
+	License     string        `yaml:"license,omitempty"`
+}
+
+type entrypointV1 struct {
+	Executable  string `yaml:"executable,omitempty"`
+	DefaultArgs argsV1 `yaml:"defaultArgs,omitempty"`
+}
+
+// argsV1 is defaultArgs written either as a command line or as a list
+type argsV1 Args
+
+func (a *argsV1) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Decode(&a.Line)
+	case yaml.SequenceNode:
+		a.List = []string{}
+		return node.Decode(&a.List)
+	}
+	return &yaml.TypeError{Errors: []string{
+		fmt.Sprintf("line %d: defaultArgs must be a string or a list of strings", node.Line),
+	}}
+}
+
+func (a argsV1) MarshalYAML() (interface{}, error) {
+	if Args(a).IsList() {
+		return a.List, nil
+	}
+	return a.Line, nil
+}
+
+func (a argsV1) IsZero() bool {
+	return Args(a).Empty()
+}
+
+type capabilityV1 struct {
+	Description string    `yaml:"description,omitempty"`
+	Lifecycle   Lifecycle `yaml:"lifecycle,omitempty"`
+	Inputs      []Input   `yaml:"inputs,omitempty"`
+	Outputs     []Output  `yaml:"outputs,omitempty"`
+}
+
+func (d *providerV1) toProvider() (*Provider, error) {
+	p := &Provider{
+		APIVersion: V1,
+		Kind:       d.Kind,
+		Metadata: Metadata{
+			Name:        d.Metadata.Name,
+			Version:     d.Metadata.Version,
+			Description: d.Metadata.Description,
+			Homepage:    d.Metadata.Homepage,
+			License:     d.Metadata.License,
+		},
+		Distribution: d.Distribution,
+		Runtime:      d.Runtime,
+		Entrypoint: Entrypoint{
+			Executable:  d.Entrypoint.Executable,
+			DefaultArgs: Args(d.Entrypoint.DefaultArgs),
+		},
+		Platforms:    d.Platforms,
+		Layers:       d.Layers,
+		Assets:       d.Assets,
+		Architecture: d.Architecture,
+		Models:       d.Models,
+		Dependencies: d.Dependencies,
+	}
+
+	for _, m := range d.Metadata.Maintainers {
+		p.Metadata.Maintainers = append(p.Metadata.Maintainers, parseMaintainerV1(m))
+	}
+
+	if d.Capabilities != nil {
+		p.Capabilities = make(map[string]Capability, len(d.Capabilities))
+		for name, c := range d.Capabilities {
+			p.Capabilities[name] = Capability{
+				Description: c.Description,
+				Lifecycle:   c.Lifecycle,
+				Inputs:      c.Inputs,
+				Outputs:     c.Outputs,
+			}
+		}
+	}
+	return p, nil
+}
+
+func fromProviderV1(p *Provider) (*providerV1, error) {
+	d := &providerV1{
+		APIVersion: V1,
+		Kind:       p.Kind,
+		Metadata: metadataV1{
+			Name:        p.Metadata.Name,
+			Version:     p.Metadata.Version,
+			Description: p.Metadata.Description,
+			Homepage:    p.Metadata.Homepage,
+			License:     p.Metadata.License,
+		},
+		Distribution: p.Distribution,
+		Runtime:      p.Runtime,
+		Entrypoint: entrypointV1{
+			Executable:  p.Entrypoint.Executable,
+			DefaultArgs: argsV1(p.Entrypoint.DefaultArgs),
+		},
+		Platforms:    p.Platforms,
+		Layers:       p.Layers,
+		Assets:       p.Assets,
+		Architecture: p.Architecture,
+		Models:       p.Models,
+		Dependencies: p.Dependencies,
+	}
+
+	for _, m := range p.Metadata.Maintainers {
+		if m.URL == "" {
+			d.Metadata.Maintainers = append(d.Metadata.Maintainers, m.String())
+		} else {
+			d.Metadata.Maintainers = append(d.Metadata.Maintainers, m)
+		}
+	}
+
+	if p.Capabilities != nil {
+		d.Capabilities = make(map[string]capabilityV1, len(p.Capabilities))
+		for name, c := range p.Capabilities {
+			if c.Entrypoint != nil {
+				return nil, fmt.Errorf("capability %s has its own entrypoint, which %s cannot express", name, V1)
+			}
+			d.Capabilities[name] = capabilityV1{
+				Description: c.Description,
+				Lifecycle:   c.Lifecycle,
+				Inputs:      c.Inputs,
+				Outputs:     c.Outputs,
+			}
+		}
+	}
+	return d, nil
+}
+
+// parseMaintainerV1 accepts "Name <email>" strings and name/email/url maps
+func parseMaintainerV1(v interface{}) Maintainer {
+	switch v := v.(type) {
+	case string:
+		if name, rest, ok := strings.Cut(v, "<"); ok && strings.HasSuffix(rest, ">") {
+			return Maintainer{Name: strings.TrimSpace(name), Email: strings.TrimSuffix(rest, ">")}
+		}
+		if strings.Contains(v, "@") && !strings.Contains(v, " ") {
+			return Maintainer{Email: v}
+		}
+		return Maintainer{Name: v}
+	case map[string]interface{}:
+		str := func(key string) string {
+			s, _ := v[key].(string)
+			return s
+		}
+		return Maintainer{Name: str("name"), Email: str("email"), URL: str("url")}
+	}
+	return Maintainer{Name: fmt.Sprint(v)}
+}
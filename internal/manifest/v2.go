@@ -0,0 +1,159 @@
+package manifest
+
+import "fmt"
+
+// providerV2 is the thin.io/v2 wire format. Compared to v1 it takes default
+// args as a list, typed maintainers, and per-capability entrypoints.
+type providerV2 struct {
+	APIVersion   string                  `yaml:"apiVersion"`
+	Kind         string                  `yaml:"kind"`
+	Metadata     metadataV2              `yaml:"metadata"`
+	Distribution Distribution            `yaml:"distribution"`
+	Runtime      Runtime                 `yaml:"runtime,omitempty"`
+	Entrypoint   entrypointV2            `yaml:"entrypoint,omitempty"`
+	Platforms    []Platform              `yaml:"platforms,omitempty"`
+	Layers       map[string]interface{}  `yaml:"layers,omitempty"`
+	Capabilities map[string]capabilityV2 `yaml:"capabilities"`
+	Assets       Assets                  `yaml:"assets,omitempty"`
+	Architecture Architecture            `yaml:"architecture,omitempty"`
+	Models       map[string]interface{}  `yaml:"models,omitempty"`
+	Dependencies []Dependency            `yaml:"dependencies,omitempty"`
+}
+
+type metadataV2 struct {
+	Name        string       `yaml:"name"`
+	Version     string       `yaml:"version"`
+	Description string       `yaml:"description,omitempty"`
+	Homepage    string       `yaml:"homepage,omitempty"`
+	Maintainers []Maintainer `yaml:"maintainers,omitempty"`
+	License     string       `yaml:"license,omitempty"`
+}
+
+type entrypointV2 struct {
+	Executable  string   `yaml:"executable,omitempty"`
+	DefaultArgs []string `yaml:"defaultArgs,omitempty"`
+}
+
+type capabilityV2 struct {
+	Description string        `yaml:"description,omitempty"`
+	Lifecycle   Lifecycle     `yaml:"lifecycle,omitempty"`
+	Inputs      []Input       `yaml:"inputs,omitempty"`
+	Outputs     []Output      `yaml:"outputs,omitempty"`
+	Entrypoint  *entrypointV2 `yaml:"entrypoint,omitempty"`
+}
+
+func (e entrypointV2) toEntrypoint() Entrypoint {
+	ep := Entrypoint{Executable: e.Executable}
+	if e.DefaultArgs != nil {
+		ep.DefaultArgs.List = e.DefaultArgs
+	}
+	return ep
+}
+
+func fromEntrypointV2(ep Entrypoint, field string) (entrypointV2, error) {
+	e := entrypointV2{Executable: ep.Executable}
+	if ep.DefaultArgs.IsList() {
+		e.DefaultArgs = ep.DefaultArgs.List
+		return e, nil
+	}
+	if ep.DefaultArgs.Line != "" {
+		args, err := SplitTemplateArgs(ep.DefaultArgs.Line)
+		if err != nil {
+			return e, fmt.Errorf("%s.defaultArgs: %w", field, err)
+		}
+		e.DefaultArgs = args
+	}
+	return e, nil
+}
+
+func (d *providerV2) toProvider() (*Provider, error) {
+	p := &Provider{
+		APIVersion: V2,
+		Kind:       d.Kind,
+		Metadata: Metadata{
+			Name:        d.Metadata.Name,
+			Version:     d.Metadata.Version,
+			Description: d.Metadata.Description,
+			Homepage:    d.Metadata.Homepage,
+			Maintainers: d.Metadata.Maintainers,
+			License:     d.Metadata.License,
+		},
+		Distribution: d.Distribution,
+		Runtime:      d.Runtime,
+		Entrypoint:   d.Entrypoint.toEntrypoint(),
+		Platforms:    d.Platforms,
+		Layers:       d.Layers,
+		Assets:       d.Assets,
+		Architecture: d.Architecture,
+		Models:       d.Models,
+		Dependencies: d.Dependencies,
+	}
+
+	if d.Capabilities != nil {
+		p.Capabilities = make(map[string]Capability, len(d.Capabilities))
+		for name, c := range d.Capabilities {
+			capability := Capability{
+				Description: c.Description,
+				Lifecycle:   c.Lifecycle,
+				Inputs:      c.Inputs,
+				Outputs:     c.Outputs,
+			}
+			if c.Entrypoint != nil {
+				ep := c.Entrypoint.toEntrypoint()
+				capability.Entrypoint = &ep
+			}
+			p.Capabilities[name] = capability
+		}
+	}
+	return p, nil
+}
+
+func fromProviderV2(p *Provider) (*providerV2, error) {
+	entrypoint, err := fromEntrypointV2(p.Entrypoint, "entrypoint")
+	if err != nil {
+		return nil, err
+	}
+
+	d := &providerV2{
+		APIVersion: V2,
+		Kind:       p.Kind,
+		Metadata: metadataV2{
+			Name:        p.Metadata.Name,
+			Version:     p.Metadata.Version,
+			Description: p.Metadata.Description,
+			Homepage:    p.Metadata.Homepage,
+			Maintainers: p.Metadata.Maintainers,
+			License:     p.Metadata.License,
+		},
+		Distribution: p.Distribution,
+		Runtime:      p.Runtime,
+		Entrypoint:   entrypoint,
+		Platforms:    p.Platforms,
+		Layers:       p.Layers,
+		Assets:       p.Assets,
+		Architecture: p.Architecture,
+		Models:       p.Models,
+		Dependencies: p.Dependencies,
+	}
+
+	if p.Capabilities != nil {
+		d.Capabilities = make(map[string]capabilityV2, len(p.Capabilities))
+		for name, c := range p.Capabilities {
+			capability := capabilityV2{
+				Description: c.Description,
+				Lifecycle:   c.Lifecycle,
+				Inputs:      c.Inputs,
+				Outputs:     c.Outputs,
+			}
+			if c.Entrypoint != nil {
+				ep, err := fromEntrypointV2(*c.Entrypoint, "capabilities."+name+".entrypoint")
+				if err != nil {
+					return nil, err
+				}
+				capability.Entrypoint = &ep
+			}
+			d.Capabilities[name] = capability
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,161 @@
+package runtime
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// assetsSumFile records the asset checksums taken at install time, in
+// sha256sum format, next to thin.provider.yaml
+const assetsSumFile = ".assets.sha256"
+
+// AssetsDir returns the provider's assets directory (assets.root, default "assets")
+func (m *ProviderManifest) AssetsDir(providerDir string) string {
+	root := m.Assets.Root
+	if root == "" {
+		root = "assets"
+	}
+	return filepath.Join(providerDir, filepath.FromSlash(root))
+}
+
+// AssetsImmutable reports whether the manifest declares its assets immutable.
+// assets.immutability may be a bool, a string ("strict", "enforced", "immutable",
+// "true") or a map with an enabled/enforced/strict bool or a mode string.
+func (m *ProviderManifest) AssetsImmutable() bool {
+	return immutabilityEnabled(m.Assets.Immutability)
+}
+
+func immutabilityEnabled(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		switch strings.ToLower(v) {
+		case "true", "strict", "enforced", "immutable", "readonly", "read-only":
+			return true
+		}
+	case map[string]interface{}:
+		for _, key := range []string{"enabled", "enforced", "strict"} {
+			if b, ok := v[key].(bool); ok {
+				return b
+			}
+		}
+		if mode, ok := v["mode"]; ok {
+			return immutabilityEnabled(mode)
+		}
+	}
+	return false
+}
+
+// SealAssets records checksums of the provider's assets and makes them
+// read-only. Entries listed in assets.contains that are missing are reported
+// as an error.
+func SealAssets(providerDir string, manifest *ProviderManifest) error {
+	assetsDir := manifest.AssetsDir(providerDir)
+
+	for _, entry := range manifest.Assets.Contains {
+		if _, err := os.Stat(filepath.Join(assetsDir, filepath.FromSlash(entry))); err != nil {
+			return fmt.Errorf("asset %s declared in assets.contains is missing", entry)
+		}
+	}
+
+	sums, err := hashTree(assetsDir, nil)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to hash assets: %w", err)
+	}
+
+	paths := make([]string, 0, len(sums))
+	for path := range sums {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&b, "%s  %s\n", sums[path], path)
+	}
+	sumPath := filepath.Join(providerDir, assetsSumFile)
+	if err := os.WriteFile(sumPath, []byte(b.String()), 0444); err != nil {
+		return fmt.Errorf("failed to record asset checksums: %w", err)
+	}
+
+	return setTreeWritable(assetsDir, false)
+}
+
+// UnsealAssets makes sealed assets writable again so a reinstall can replace them
+func UnsealAssets(providerDir string) error {
+	sumPath := filepath.Join(providerDir, assetsSumFile)
+	if _, err := os.Stat(sumPath); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.Remove(sumPath); err != nil {
+		return err
+	}
+
+	manifest, err := ReadProviderManifest(providerDir)
+	if err != nil || manifest == nil {
+		// Fall back to the default location if the old manifest is unreadable
+		return setTreeWritable(filepath.Join(providerDir, "assets"), true)
+	}
+	return setTreeWritable(manifest.AssetsDir(providerDir), true)
+}
+
+// AssetsTamperedError is returned when immutable assets no longer match the
+// checksums recorded at install time
+type AssetsTamperedError struct {
+	Provider string
+	Diff     *TreeDiff
+}
+
+func (e *AssetsTamperedError) Error() string {
+	return fmt.Sprintf("immutable assets of provider %s have been tampered with (%s); reinstall the provider to restore them",
+		e.Provider, e.Diff)
+}
+
+// VerifyAssets checks immutable assets against the checksums recorded at
+// install time. It is a no-op for providers that don't declare immutability.
+func VerifyAssets(providerDir string, manifest *ProviderManifest) error {
+	if !manifest.AssetsImmutable() {
+		return nil
+	}
+
+	recorded, err := readAssetSums(filepath.Join(providerDir, assetsSumFile))
+	if err != nil {
+		return fmt.Errorf("provider %s declares immutable assets but has no recorded checksums (reinstall the provider): %w",
+			manifest.Metadata.Name, err)
+	}
+
+	current, err := hashTree(manifest.AssetsDir(providerDir), nil)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to hash assets: %w", err)
+	}
+
+	if diff := diffTrees(recorded, current); !diff.Empty() {
+		return &AssetsTamperedError{Provider: manifest.Metadata.Name, Diff: diff}
+	}
+	return nil
+}
+
+// readAssetSums parses a sha256sum-format file
+func readAssetSums(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		sum, rel, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			continue
+		}
+		sums[rel] = sum
+	}
+	return sums, scanner.Err()
+}
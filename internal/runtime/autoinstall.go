@@ -0,0 +1,147 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// ProviderMismatchError is returned when the provider installed where ref
+// would run from was installed from a different image than ref asks for
+type ProviderMismatchError struct {
+	Ref       *ProviderRef
+	Dir       string
+	Installed string // image reference or version that is installed
+	Wanted    string // image reference ref resolves to
+}
+
+func (e *ProviderMismatchError) Error() string {
+	return fmt.Sprintf("provider %s is installed at %s from %s, not %s; remove it with 'thin provider remove %s' to install %s",
+		e.Ref.Name, e.Dir, e.Installed, e.Wanted, e.Ref.Name, e.Wanted)
+}
+
+// InstalledProvider reports whether the provider ref runs from is installed.
+// When source is not nil the installed provider must have been installed
+// from it (compared through its install receipt, or its manifest version
+// when it has no receipt): a different provider or version installed under
+// the same name is a ProviderMismatchError rather than running in its place.
+func InstalledProvider(ref *ProviderRef, source *ProviderSource) (bool, error) {
+	dir := ProviderDir(ref)
+	if _, err := os.Stat(filepath.Join(dir, "thin.provider.yaml")); err != nil {
+		return false, nil
+	}
+	if source == nil {
+		return true, nil
+	}
+
+	wanted, err := ParseImageReference(source.ImageRef)
+	if err != nil {
+		return true, err
+	}
+	mismatch := &ProviderMismatchError{Ref: ref, Dir: dir, Wanted: wanted.String()}
+
+	if receipt, err := ReadReceipt(dir); err == nil {
+		mismatch.Installed = receipt.Source
+		installed, err := ParseImageReference(receipt.Source)
+		if err != nil || installed.Name() != wanted.Name() {
+			return true, mismatch
+		}
+		if wanted.Digest != "" {
+			if receipt.ManifestDigest != wanted.Digest {
+				return true, mismatch
+			}
+		} else if installed.Tag != wanted.Tag {
+			return true, mismatch
+		}
+		return true, nil
+	}
+
+	// Providers installed without a receipt only have a version to go by
+	manifest, err := ReadProviderManifest(dir)
+	if err != nil || manifest == nil {
+		return true, err
+	}
+	if strings.TrimPrefix(manifest.Metadata.Version, "v") != strings.TrimPrefix(ref.Version, "v") {
+		mismatch.Installed = "version " + manifest.Metadata.Version
+		return true, mismatch
+	}
+	return true, nil
+}
+
+// NestedInstalls returns the directories of providers named name that are
+// installed in the namespace/name/version layout. Installing name in the
+// flat layout would take their place.
+func NestedInstalls(name string) []string {
+	manifests, _ := filepath.Glob(filepath.Join(DataHome(), "providers", "*", name, "*", "thin.provider.yaml"))
+	dirs := make([]string, 0, len(manifests))
+	for _, m := range manifests {
+		dirs = append(dirs, filepath.Dir(m))
+	}
+	return dirs
+}
+
+// ProviderSource is where an uninstalled provider can be installed from
+type ProviderSource struct {
+	ImageRef string // image reference including the version tag or digest
+	Origin   string // how it was found: "reference", "config", "project" or "manifest"
+}
+
+// Trusted reports whether the source may be installed from without asking.
+// Sources from project config come with the repository thin is run in.
+func (s *ProviderSource) Trusted() bool {
+	return s.Origin != "project"
+}
+
+// FindProviderSource works out the image a provider reference is published
+// as: from the registry in the reference itself, from the sources mapping
+// in config, or from the distribution.ref of another installed version of
+// the same provider. Returns false when none of them knows.
+func FindProviderSource(ref *ProviderRef, cfg *Config) (*ProviderSource, bool) {
+	name := ref.Namespace + "/" + ref.Name
+
+	switch {
+	case ref.Registry != "":
+		return &ProviderSource{ImageRef: versionedImageRef(ref.Registry+"/"+name, ref.Version), Origin: "reference"}, true
+	case cfg.Sources[name] != "":
+		origin := "config"
+		if cfg.projectSources[name] {
+			origin = "project"
+		}
+		return &ProviderSource{ImageRef: versionedImageRef(cfg.Sources[name], ref.Version), Origin: origin}, true
+	}
+
+	if repository := installedDistributionRef(ref); repository != "" {
+		return &ProviderSource{ImageRef: versionedImageRef(repository, ref.Version), Origin: "manifest"}, true
+	}
+	return nil, false
+}
+
+// versionedImageRef appends a provider version to a repository, as a
+// digest if it is one and as a tag otherwise
+func versionedImageRef(repository, version string) string {
+	repository = strings.TrimSuffix(repository, "/")
+	if _, err := digest.Parse(version); err == nil {
+		return repository + "@" + version
+	}
+	return repository + ":" + version
+}
+
+// installedDistributionRef returns the OCI distribution.ref declared by any
+// installed version of ref's provider
+func installedDistributionRef(ref *ProviderRef) string {
+	dirs, _ := filepath.Glob(filepath.Join(DataHome(), "providers", ref.Namespace, ref.Name, "*"))
+	for _, dir := range dirs {
+		manifest, err := ReadProviderManifest(dir)
+		if err != nil || manifest == nil {
+			continue
+		}
+		d := manifest.Distribution
+		if d.Ref != "" && (d.Type == "" || d.Type == "oci") {
+			return d.Ref
+		}
+	}
+	return ""
+}
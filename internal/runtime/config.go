@@ -0,0 +1,224 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds user- and project-level thin settings
+//
+// User config lives at config.yaml in the config home (see ConfigHome).
+// Project config lives at thin.yaml or .thin/config.yaml in the project root
+// (see ProjectRoot) and takes precedence over user config.
+type Config struct {
+	// Aliases maps a command name to the arguments it expands to,
+	// e.g. "plan-prod: lite-ci plan --env prod"
+	Aliases map[string]string `yaml:"aliases"`
+
+	// Verify set to "strict" checks installed providers against their install
+	// receipt before every run. THIN_VERIFY overrides it. Project config can
+	// only turn strict verification on.
+	Verify string `yaml:"verify"`
+
+	// Vars are free-form values available to manifest templates as .Vars,
+	// e.g. "region: eu-west-1". Project vars override user vars by key.
+	Vars map[string]interface{} `yaml:"vars"`
+
+	// Hooks run around matching provider invocations. User hooks run before
+	// project hooks.
+	Hooks Hooks `yaml:"hooks"`
+
+	// Registries holds connection settings keyed by registry host,
+	// e.g. "localhost:5000" or "docker.io". Project entries replace user
+	// entries for the same host, but only with TrustProjectRegistries.
+	Registries map[string]RegistryConfig `yaml:"registries"`
+
+	// Rewrites map image references to other locations before pulling.
+	// The first matching rule wins; project rules are tried before user
+	// rules, but only with TrustProjectRegistries.
+	Rewrites []Rewrite `yaml:"rewrites"`
+
+	// TrustProjectRegistries lets project config set registries and
+	// rewrites, which can redirect pulls or turn off TLS verification.
+	// Only read from user config.
+	TrustProjectRegistries bool `yaml:"trustProjectRegistries"`
+
+	// Sources map provider names (namespace/name) to the OCI repository
+	// they are published at, e.g. "acme/ci: ghcr.io/acme/ci", so that
+	// uninstalled providers can be installed on first use. Project sources
+	// only add names the user hasn't mapped, and are never installed from
+	// without asking.
+	Sources map[string]string `yaml:"sources"`
+
+	// AutoInstall decides what happens when a provider that isn't installed
+	// is run: "prompt" (default) asks in interactive sessions, "always"
+	// installs without asking and "never" fails. THIN_AUTO_INSTALL overrides
+	// it. Project config can't set "always".
+	AutoInstall string `yaml:"autoInstall"`
+
+	// projectSources are the names in Sources that came from project config
+	projectSources map[string]bool
+
+	// Retry controls how failed registry requests are retried. Project
+	// settings override user settings field by field.
+	Retry RetryConfig `yaml:"retry"`
+}
+
+// StrictVerify reports whether providers must match their install receipt
+// before they are run
+func (c *Config) StrictVerify() bool {
+	mode := c.Verify
+	if v := os.Getenv("THIN_VERIFY"); v != "" {
+		mode = v
+	}
+	return mode == "strict"
+}
+
+// Auto-install policies
+const (
+	AutoInstallPrompt = "prompt"
+	AutoInstallAlways = "always"
+	AutoInstallNever  = "never"
+)
+
+// AutoInstallPolicy returns the auto-install policy
+func (c *Config) AutoInstallPolicy() (string, error) {
+	policy := c.AutoInstall
+	if v := os.Getenv("THIN_AUTO_INSTALL"); v != "" {
+		policy = v
+	}
+	switch policy {
+	case "":
+		return AutoInstallPrompt, nil
+	case AutoInstallPrompt, AutoInstallAlways, AutoInstallNever:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid auto-install policy %q (expected %s, %s or %s)",
+		policy, AutoInstallPrompt, AutoInstallAlways, AutoInstallNever)
+}
+
+// UserConfigPath returns the path of the user-level config file
+func UserConfigPath() string {
+	return filepath.Join(ConfigHome(), "config.yaml")
+}
+
+// ProjectConfigPaths returns candidate project-level config files, in
+// order of precedence
+// Returns nil when thin is not run inside a project
+func ProjectConfigPaths() []string {
+	root := ProjectRoot()
+	if root == "" {
+		return nil
+	}
+	return []string{
+		filepath.Join(root, "thin.yaml"),
+		filepath.Join(root, ".thin", "config.yaml"),
+	}
+}
+
+// LoadConfig reads the user config and overlays the project config on top
+// Missing config files are not an error
+func LoadConfig() (*Config, error) {
+	cfg := &Config{
+		Aliases:    map[string]string{},
+		Vars:       map[string]interface{}{},
+		Registries: map[string]RegistryConfig{},
+		Sources:    map[string]string{},
+
+		projectSources: map[string]bool{},
+	}
+
+	user, err := readConfigFile(UserConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	cfg.merge(user, false)
+
+	for _, path := range ProjectConfigPaths() {
+		project, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if project != nil {
+			cfg.merge(project, true)
+			break
+		}
+	}
+
+	return cfg, nil
+}
+
+// readConfigFile parses a single config file
+// Returns nil if the file doesn't exist
+func readConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	// Certificate paths are relative to the config file
+	for host, registry := range cfg.Registries {
+		registry.resolvePaths(filepath.Dir(path))
+		cfg.Registries[host] = registry
+	}
+	return &cfg, nil
+}
+
+// merge overlays other on top of c. Settings from a project config, which
+// comes with whatever repository thin is run in, can't loosen the user's.
+func (c *Config) merge(other *Config, project bool) {
+	if other == nil {
+		return
+	}
+	for name, expansion := range other.Aliases {
+		c.Aliases[name] = expansion
+	}
+	for name, value := range other.Vars {
+		c.Vars[name] = value
+	}
+	for name, source := range other.Sources {
+		if project {
+			if _, ok := c.Sources[name]; ok {
+				continue
+			}
+			c.projectSources[name] = true
+		}
+		c.Sources[name] = source
+	}
+	if other.AutoInstall != "" && !(project && other.AutoInstall == AutoInstallAlways) {
+		c.AutoInstall = other.AutoInstall
+	}
+	if !project {
+		c.TrustProjectRegistries = other.TrustProjectRegistries
+	}
+	if !project || c.TrustProjectRegistries {
+		for host, registry := range other.Registries {
+			c.Registries[host] = registry
+		}
+		c.Rewrites = append(append([]Rewrite{}, other.Rewrites...), c.Rewrites...)
+	}
+	c.Hooks.Pre = append(c.Hooks.Pre, other.Hooks.Pre...)
+	c.Hooks.Post = append(c.Hooks.Post, other.Hooks.Post...)
+	if other.Retry.Attempts != 0 {
+		c.Retry.Attempts = other.Retry.Attempts
+	}
+	if other.Retry.InitialDelay != 0 {
+		c.Retry.InitialDelay = other.Retry.InitialDelay
+	}
+	if other.Retry.MaxDelay != 0 {
+		c.Retry.MaxDelay = other.Retry.MaxDelay
+	}
+	if other.Verify != "" && (!project || other.Verify == "strict") {
+		c.Verify = other.Verify
+	}
+}
@@ -0,0 +1,160 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useTempHomes points the data, cache and config homes at a temporary
+// directory and moves into an empty repository there, so tests never touch
+// the real stores or pick up a project around the working directory. It
+// returns the repository directory.
+func useTempHomes(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("THIN_HOME", "")
+	t.Setenv("THIN_DATA_HOME", filepath.Join(root, "data"))
+	t.Setenv("THIN_CACHE_HOME", filepath.Join(root, "cache"))
+	t.Setenv("THIN_CONFIG_HOME", filepath.Join(root, "config"))
+	t.Setenv("THIN_VERIFY", "")
+	t.Setenv("THIN_AUTO_INSTALL", "")
+
+	repo := filepath.Join(root, "repo")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return repo
+}
+
+func TestLoadConfigProjectTrust(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		project string
+		check   func(t *testing.T, cfg *Config)
+	}{
+		{
+			name:    "project adds aliases and vars",
+			user:    "aliases: {p: lite-ci plan}\nvars: {region: us-east-1, team: a}",
+			project: "aliases: {d: lite-ci deploy}\nvars: {region: eu-west-1}",
+			check: func(t *testing.T, cfg *Config) {
+				want := map[string]string{"p": "lite-ci plan", "d": "lite-ci deploy"}
+				if !reflect.DeepEqual(cfg.Aliases, want) {
+					t.Errorf("Aliases = %v, want %v", cfg.Aliases, want)
+				}
+				if cfg.Vars["region"] != "eu-west-1" || cfg.Vars["team"] != "a" {
+					t.Errorf("Vars = %v, want project region over user vars", cfg.Vars)
+				}
+			},
+		},
+		{
+			name:    "project can turn strict verification on",
+			project: "verify: strict",
+			check: func(t *testing.T, cfg *Config) {
+				if !cfg.StrictVerify() {
+					t.Error("StrictVerify() = false, want true")
+				}
+			},
+		},
+		{
+			name:    "project can't turn strict verification off",
+			user:    "verify: strict",
+			project: "verify: off",
+			check: func(t *testing.T, cfg *Config) {
+				if !cfg.StrictVerify() {
+					t.Error("StrictVerify() = false, want the user's strict")
+				}
+			},
+		},
+		{
+			name:    "project can't set autoInstall always",
+			user:    "autoInstall: never",
+			project: "autoInstall: always",
+			check: func(t *testing.T, cfg *Config) {
+				if policy, _ := cfg.AutoInstallPolicy(); policy != AutoInstallNever {
+					t.Errorf("AutoInstallPolicy() = %q, want %q", policy, AutoInstallNever)
+				}
+			},
+		},
+		{
+			name:    "project sources only add names and aren't trusted",
+			user:    "sources: {acme/ci: ghcr.io/acme/ci}",
+			project: "sources: {acme/ci: evil.example/ci, acme/lint: ghcr.io/acme/lint}",
+			check: func(t *testing.T, cfg *Config) {
+				source, _ := FindProviderSource(&ProviderRef{Namespace: "acme", Name: "ci", Version: "v1"}, cfg)
+				if source.ImageRef != "ghcr.io/acme/ci:v1" || !source.Trusted() {
+					t.Errorf("acme/ci source = %+v, want the user's, trusted", source)
+				}
+				source, _ = FindProviderSource(&ProviderRef{Namespace: "acme", Name: "lint", Version: "v1"}, cfg)
+				if source.ImageRef != "ghcr.io/acme/lint:v1" || source.Trusted() {
+					t.Errorf("acme/lint source = %+v, want the project's, untrusted", source)
+				}
+			},
+		},
+		{
+			name:    "project registries and rewrites are ignored",
+			user:    "registries: {ghcr.io: {mirrors: [mirror.example]}}",
+			project: "registries: {ghcr.io: {insecure: true}}\nrewrites: [{from: ghcr.io/acme, to: evil.example/acme}]\ntrustProjectRegistries: true",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Registries["ghcr.io"].Insecure || len(cfg.Rewrites) != 0 || cfg.TrustProjectRegistries {
+					t.Errorf("project registry settings applied: %+v, rewrites %+v", cfg.Registries, cfg.Rewrites)
+				}
+			},
+		},
+		{
+			name:    "trusted project registries and rewrites",
+			user:    "trustProjectRegistries: true\nrewrites: [{from: docker.io, to: mirror.example}]",
+			project: "registries: {registry.internal: {plainHTTP: true}}\nrewrites: [{from: ghcr.io/acme, to: registry.internal/acme}]",
+			check: func(t *testing.T, cfg *Config) {
+				if !cfg.Registries["registry.internal"].PlainHTTP {
+					t.Errorf("Registries = %+v, want the project's registry.internal", cfg.Registries)
+				}
+				if len(cfg.Rewrites) != 2 || cfg.Rewrites[0].From != "ghcr.io/acme" {
+					t.Errorf("Rewrites = %+v, want the project's rule first", cfg.Rewrites)
+				}
+			},
+		},
+		{
+			name:    "hooks run user first",
+			user:    "hooks: {pre: [{run: user}]}",
+			project: "hooks: {pre: [{run: project}]}",
+			check: func(t *testing.T, cfg *Config) {
+				if len(cfg.Hooks.Pre) != 2 || cfg.Hooks.Pre[0].Run != "user" || cfg.Hooks.Pre[1].Run != "project" {
+					t.Errorf("Hooks.Pre = %+v, want user then project", cfg.Hooks.Pre)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := useTempHomes(t)
+			if tt.user != "" {
+				if err := os.MkdirAll(ConfigHome(), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(UserConfigPath(), []byte(tt.user), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(repo, "thin.yaml"), []byte(tt.project), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig error: %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
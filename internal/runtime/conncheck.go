@@ -0,0 +1,187 @@
+package runtime
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"oras.land/oras-go/v2/registry"
+)
+
+// ConnectionCheck is the result of probing a registry's /v2/ endpoint
+type ConnectionCheck struct {
+	Registry     string   `json:"registry" yaml:"registry"`
+	URL          string   `json:"url" yaml:"url"`
+	Proxy        string   `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	OK           bool     `json:"ok" yaml:"ok"`
+	Status       int      `json:"status,omitempty" yaml:"status,omitempty"`
+	AuthRequired bool     `json:"authRequired,omitempty" yaml:"authRequired,omitempty"`
+	TLS          *TLSInfo `json:"tls,omitempty" yaml:"tls,omitempty"`
+	LatencyMs    int64    `json:"latencyMs" yaml:"latencyMs"`
+	Problem      string   `json:"problem,omitempty" yaml:"problem,omitempty"`
+	Hint         string   `json:"hint,omitempty" yaml:"hint,omitempty"`
+}
+
+// TLSInfo describes the TLS session negotiated with a registry
+type TLSInfo struct {
+	Version  string    `json:"version" yaml:"version"`
+	Subject  string    `json:"subject" yaml:"subject"`
+	Issuer   string    `json:"issuer" yaml:"issuer"`
+	NotAfter time.Time `json:"notAfter" yaml:"notAfter"`
+	Verified bool      `json:"verified" yaml:"verified"`
+}
+
+// RegistryHosts returns the registries an image reference or registry host
+// is pulled from after rewrites: its mirrors, then the registry itself
+func RegistryHosts(ref string) []string {
+	repository := ref
+	if strings.Contains(ref, "/") {
+		parsed, err := ParseImageReference(ref)
+		if err == nil {
+			repository = parsed.Name()
+		}
+	}
+	seen := map[string]bool{}
+	var hosts []string
+	for _, source := range pullSources(repository) {
+		host := registryHost(source.Repository)
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// CheckConnection probes the /v2/ endpoint of a registry host with the same
+// proxy and TLS settings used for pulls, and explains what went wrong
+func CheckConnection(ctx context.Context, host string) *ConnectionCheck {
+	settings := registryConfig(host)
+	scheme := "https"
+	if settings.PlainHTTP {
+		scheme = "http"
+	}
+	endpoint := fmt.Sprintf("%s://%s/v2/", scheme, registry.Reference{Registry: host}.Host())
+	check := &ConnectionCheck{Registry: host, URL: endpoint}
+
+	transport, err := newTransport(host, settings)
+	if err != nil {
+		check.Problem = err.Error()
+		check.Hint = fmt.Sprintf("fix registries.%s in the thin config", host)
+		return check
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		check.Problem = err.Error()
+		return check
+	}
+	if proxy, err := transport.Proxy(req); err == nil && proxy != nil {
+		check.Proxy = proxy.Redacted()
+	}
+
+	client := &http.Client{Transport: transport, Timeout: 30 * time.Second}
+	start := time.Now()
+	resp, err := client.Do(req)
+	check.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		check.Problem, check.Hint = explainConnectionError(err, host, check.Proxy != "")
+		return check
+	}
+	resp.Body.Close()
+
+	check.Status = resp.StatusCode
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert := resp.TLS.PeerCertificates[0]
+		check.TLS = &TLSInfo{
+			Version:  tls.VersionName(resp.TLS.Version),
+			Subject:  cert.Subject.String(),
+			Issuer:   cert.Issuer.String(),
+			NotAfter: cert.NotAfter,
+			Verified: !settings.Insecure,
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		check.OK = true
+	case http.StatusUnauthorized:
+		// Anonymous /v2/ requests are challenged by most registries
+		check.OK = true
+		check.AuthRequired = true
+	default:
+		check.Problem = fmt.Sprintf("unexpected HTTP status %s", resp.Status)
+		check.Hint = fmt.Sprintf("%s does not look like an OCI registry endpoint", endpoint)
+		if check.Proxy != "" {
+			check.Hint += "; the proxy may be answering instead of the registry"
+		}
+	}
+	return check
+}
+
+// explainConnectionError turns a transport error into a problem statement
+// and a hint at the config that fixes it
+func explainConnectionError(err error, host string, proxied bool) (problem, hint string) {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var recordHeader tls.RecordHeaderError
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		issuer := "unknown issuer"
+		if unknownAuthority.Cert != nil {
+			issuer = unknownAuthority.Cert.Issuer.String()
+		}
+		problem = fmt.Sprintf("TLS: certificate signed by unknown authority (%s)", issuer)
+		hint = fmt.Sprintf("add the issuing CA as registries.%s.ca; behind an inspection proxy this is the proxy's root CA", host)
+	case errors.As(err, &hostnameErr):
+		problem = "TLS: " + hostnameErr.Error()
+		hint = "check rewrites and mirrors point at the registry's real host name"
+	case errors.As(err, &invalidCert):
+		problem = "TLS: " + invalidCert.Error()
+		hint = fmt.Sprintf("renew the registry certificate, or set registries.%s.insecure for testing", host)
+	case errors.As(err, &recordHeader):
+		problem = "TLS: the server did not answer with TLS"
+		hint = fmt.Sprintf("set registries.%s.plainHTTP if the registry serves plain http", host)
+	case strings.Contains(err.Error(), "certificate required"), strings.Contains(err.Error(), "bad certificate"):
+		problem = "TLS: the registry rejected the client certificate"
+		hint = fmt.Sprintf("set registries.%s.cert and registries.%s.key to a certificate the registry accepts", host, host)
+	case errors.As(err, &opErr) && opErr.Op == "proxyconnect":
+		problem = "proxy: " + opErr.Err.Error()
+		hint = "check HTTPS_PROXY and HTTP_PROXY, or add the registry to NO_PROXY"
+	case errors.As(err, &dnsErr):
+		problem = fmt.Sprintf("DNS: cannot resolve %s", dnsErr.Name)
+		hint = "check the registry host name"
+		if proxied {
+			hint += "; names are resolved by the proxy only when the registry is not in NO_PROXY"
+		}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		problem = "connection refused"
+		hint = "check the registry host and port"
+	case errors.Is(err, context.DeadlineExceeded) || isTimeout(err):
+		problem = "timed out"
+		hint = "check network access to the registry"
+	default:
+		problem = err.Error()
+	}
+
+	if proxied && !strings.HasPrefix(problem, "proxy") && hint == "" {
+		hint = "the request went through a proxy; check HTTPS_PROXY and NO_PROXY"
+	}
+	return problem, hint
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,241 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// resolvedDependency records which version of a dependency was chosen and why
+type resolvedDependency struct {
+	Ref        string
+	Version    *semVersion
+	Constraint *versionConstraint
+	RequiredBy string
+}
+
+// InstalledDependency reports how one dependency in the graph was satisfied
+type InstalledDependency struct {
+	Name       string `json:"name" yaml:"name"`
+	Ref        string `json:"ref" yaml:"ref"`
+	Version    string `json:"version" yaml:"version"`
+	RequiredBy string `json:"requiredBy" yaml:"requiredBy"`
+	Reused     bool   `json:"reused" yaml:"reused"` // Already installed at a satisfying version
+}
+
+// dependencyResolver walks a provider's dependency graph, installing
+// dependencies that are missing or don't satisfy their constraint
+type dependencyResolver struct {
+	resolved  map[string]*resolvedDependency
+	installed []InstalledDependency
+}
+
+// InstallDependencies resolves and installs the dependency graph of the
+// installed provider providerName, returning every dependency in resolution
+// order. Cycles and version conflicts are errors.
+func InstallDependencies(ctx context.Context, providerName string) ([]InstalledDependency, error) {
+	r := &dependencyResolver{resolved: map[string]*resolvedDependency{}}
+	if err := r.install(ctx, providerName, []string{providerName}); err != nil {
+		return nil, err
+	}
+	return r.installed, nil
+}
+
+// install resolves the dependencies of name; path is the chain of
+// providers that led here, used for cycle detection
+func (r *dependencyResolver) install(ctx context.Context, name string, path []string) error {
+	manifest, err := ReadProviderManifest(filepath.Join(DataHome(), "providers", name))
+	if err != nil {
+		return fmt.Errorf("failed to read manifest of %s: %w", name, err)
+	}
+	if manifest == nil {
+		return nil
+	}
+
+	for _, dep := range manifest.Dependencies {
+		for _, p := range path {
+			if p == dep.Name {
+				return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), dep.Name)
+			}
+		}
+
+		constraint, err := parseVersionConstraint(dep.Version)
+		if err != nil {
+			return fmt.Errorf("%s: dependency %s: %w", name, dep.Name, err)
+		}
+
+		if prev, ok := r.resolved[dep.Name]; ok {
+			if prev.Ref != dep.Ref {
+				return fmt.Errorf("dependency conflict: %s requires %s from %s, but %s requires it from %s",
+					name, dep.Name, dep.Ref, prev.RequiredBy, prev.Ref)
+			}
+			if !constraint.Check(prev.Version) {
+				return fmt.Errorf("dependency conflict: %s requires %s %s, but %s requires %s (resolved %s)",
+					name, dep.Name, constraint, prev.RequiredBy, prev.Constraint, prev.Version)
+			}
+			continue
+		}
+
+		version, reused, err := r.ensureInstalled(ctx, dep, constraint)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		r.installed = append(r.installed, InstalledDependency{
+			Name:       dep.Name,
+			Ref:        dep.Ref,
+			Version:    version.String(),
+			RequiredBy: name,
+			Reused:     reused,
+		})
+		r.resolved[dep.Name] = &resolvedDependency{
+			Ref:        dep.Ref,
+			Version:    version,
+			Constraint: constraint,
+			RequiredBy: name,
+		}
+
+		if err := r.install(ctx, dep.Name, append(path, dep.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureInstalled reuses an installed dependency that satisfies constraint
+// (reused=true), or pulls the highest matching version from the registry
+// when it is not installed. An installed provider of the same name is
+// never overwritten: one from another repository is a
+// ProviderMismatchError, one at a version outside constraint a conflict.
+func (r *dependencyResolver) ensureInstalled(ctx context.Context, dep Dependency, constraint *versionConstraint) (version *semVersion, reused bool, err error) {
+	dir := filepath.Join(DataHome(), "providers", dep.Name)
+	if _, installed, err := GetProviderMetadata(dir); err == nil && installed != "" {
+		if err := checkDependencySource(dep, dir); err != nil {
+			return nil, false, err
+		}
+		v, err := parseSemVersion(installed)
+		if err != nil || !constraint.Check(v) {
+			return nil, false, fmt.Errorf("dependency conflict: requires %s %s, but %s %s is installed at %s; remove it with 'thin provider remove %s' to install a matching version",
+				dep.Name, constraint, dep.Name, installed, dir, dep.Name)
+		}
+		fmt.Fprintf(messageOutput, "✓ Dependency %s %s already installed\n", dep.Name, v)
+		return v, true, nil
+	}
+
+	tag, version, err := resolveDependencyTag(ctx, dep, constraint)
+	if err != nil {
+		return nil, false, err
+	}
+
+	fmt.Fprintf(messageOutput, "Installing dependency %s %s...\n", dep.Name, version)
+	if err := PullProviderOCI(ctx, dep.Ref+":"+tag, dep.Name); err != nil {
+		return nil, false, fmt.Errorf("failed to install dependency %s: %w", dep.Name, err)
+	}
+	return version, false, nil
+}
+
+// checkDependencySource makes sure the provider installed at dir was
+// installed from dep.Ref, according to its install receipt
+func checkDependencySource(dep Dependency, dir string) error {
+	mismatch := &ProviderMismatchError{Ref: &ProviderRef{Name: dep.Name}, Dir: dir, Wanted: dep.Ref}
+
+	receipt, err := ReadReceipt(dir)
+	if err != nil {
+		mismatch.Installed = "an unknown source (it has no install receipt)"
+		return mismatch
+	}
+	mismatch.Installed = receipt.Source
+
+	wanted, err := ParseImageReference(dep.Ref)
+	if err != nil {
+		return fmt.Errorf("dependency %s: %w", dep.Name, err)
+	}
+	installed, err := ParseImageReference(receipt.Source)
+	if err != nil || installed.Name() != wanted.Name() {
+		return mismatch
+	}
+	return nil
+}
+
+// resolveDependencyTag picks the highest registry tag satisfying constraint.
+// Prerelease tags are only considered when the constraint names a prerelease.
+func resolveDependencyTag(ctx context.Context, dep Dependency, constraint *versionConstraint) (string, *semVersion, error) {
+	tags, err := ListTags(ctx, dep.Ref)
+	if err != nil {
+		return "", nil, err
+	}
+
+	allowPrerelease := strings.Contains(dep.Version, "-")
+
+	type candidate struct {
+		tag     string
+		version *semVersion
+	}
+	var candidates []candidate
+	for _, tag := range tags {
+		v, err := parseSemVersion(tag)
+		if err != nil {
+			continue // not a version tag
+		}
+		if v.Prerelease != "" && !allowPrerelease {
+			continue
+		}
+		if constraint.Check(v) {
+			candidates = append(candidates, candidate{tag, v})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", nil, fmt.Errorf("no version of %s (%s) satisfies %s", dep.Name, dep.Ref, constraint)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].version.compare(candidates[j].version) > 0
+	})
+	return candidates[0].tag, candidates[0].version, nil
+}
+
+// DependencyEnv returns environment entries exposing each dependency of
+// manifest to the provider:
+//
+//	THIN_DEP_<NAME>       path to the dependency's entrypoint binary
+//	THIN_DEP_<NAME>_HOME  the dependency's install directory
+//
+// <NAME> is the dependency name upper-cased with non-alphanumerics as '_'.
+func DependencyEnv(manifest *ProviderManifest) ([]string, error) {
+	var env []string
+	for _, dep := range manifest.Dependencies {
+		depDir := filepath.Join(DataHome(), "providers", dep.Name)
+		depManifest, err := ReadProviderManifest(depDir)
+		if err != nil {
+			return nil, fmt.Errorf("dependency %s: %w", dep.Name, err)
+		}
+		if depManifest == nil {
+			return nil, fmt.Errorf("dependency %s is not installed (reinstall the provider to fetch it from %s)", dep.Name, dep.Ref)
+		}
+
+		binaryPath, err := ResolveEntrypoint(depDir, depManifest)
+		if err != nil {
+			return nil, fmt.Errorf("dependency %s: %w", dep.Name, err)
+		}
+
+		key := "THIN_DEP_" + envName(dep.Name)
+		env = append(env, key+"="+binaryPath, key+"_HOME="+depDir)
+	}
+	return env, nil
+}
+
+// envName converts a provider name to an environment variable fragment
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
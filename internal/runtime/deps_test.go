@@ -0,0 +1,159 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// installTestProvider writes an installed provider with a manifest and an
+// install receipt naming source into the data home
+func installTestProvider(t *testing.T, name, version, source string, deps ...Dependency) {
+	t.Helper()
+	dir := filepath.Join(DataHome(), "providers", name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := fmt.Sprintf(`apiVersion: thin.io/v1
+kind: Provider
+metadata: {name: %s, version: %s}
+distribution: {type: oci, ref: registry.example/acme/%[1]s}
+entrypoint: {executable: entrypoint}
+capabilities: {run: {description: run}}
+`, name, version)
+	if len(deps) > 0 {
+		manifest += "dependencies:\n"
+		for _, dep := range deps {
+			manifest += fmt.Sprintf("  - {name: %s, ref: %s, version: %q}\n", dep.Name, dep.Ref, dep.Version)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "thin.provider.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "entrypoint"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteReceipt(dir, name, source, "sha256:"+strings.Repeat("0", 64)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInstallDependencies(t *testing.T) {
+	SetMessageOutput(io.Discard)
+	defer SetMessageOutput(os.Stdout)
+
+	const secretsRef = "registry.example/acme/secrets"
+	secrets := Dependency{Name: "secrets", Ref: secretsRef, Version: "^1.2"}
+
+	tests := []struct {
+		name    string
+		install func(t *testing.T)
+		want    []InstalledDependency
+		wantErr string
+	}{
+		{
+			name: "reuse",
+			install: func(t *testing.T) {
+				installTestProvider(t, "deploy", "1.0.0", "registry.example/acme/deploy:v1.0.0", secrets)
+				installTestProvider(t, "secrets", "1.4.0", secretsRef+":v1.4.0")
+			},
+			want: []InstalledDependency{
+				{Name: "secrets", Ref: secretsRef, Version: "1.4.0", RequiredBy: "deploy", Reused: true},
+			},
+		},
+		{
+			name: "transitive reuse",
+			install: func(t *testing.T) {
+				installTestProvider(t, "deploy", "1.0.0", "registry.example/acme/deploy:v1.0.0",
+					Dependency{Name: "vault", Ref: "registry.example/acme/vault", Version: "1.x"})
+				installTestProvider(t, "vault", "1.0.0", "registry.example/acme/vault:v1.0.0", secrets)
+				installTestProvider(t, "secrets", "1.2.0", secretsRef+":v1.2.0")
+			},
+			want: []InstalledDependency{
+				{Name: "vault", Ref: "registry.example/acme/vault", Version: "1.0.0", RequiredBy: "deploy", Reused: true},
+				{Name: "secrets", Ref: secretsRef, Version: "1.2.0", RequiredBy: "vault", Reused: true},
+			},
+		},
+		{
+			name: "cycle",
+			install: func(t *testing.T) {
+				installTestProvider(t, "deploy", "1.0.0", "registry.example/acme/deploy:v1.0.0", secrets)
+				installTestProvider(t, "secrets", "1.2.0", secretsRef+":v1.2.0",
+					Dependency{Name: "deploy", Ref: "registry.example/acme/deploy", Version: "*"})
+			},
+			wantErr: "dependency cycle: deploy -> secrets -> deploy",
+		},
+		{
+			name: "installed version outside constraint",
+			install: func(t *testing.T) {
+				installTestProvider(t, "deploy", "1.0.0", "registry.example/acme/deploy:v1.0.0", secrets)
+				installTestProvider(t, "secrets", "2.0.0", secretsRef+":v2.0.0")
+			},
+			wantErr: "dependency conflict: requires secrets ^1.2, but secrets 2.0.0 is installed",
+		},
+		{
+			name: "conflicting constraints",
+			install: func(t *testing.T) {
+				installTestProvider(t, "deploy", "1.0.0", "registry.example/acme/deploy:v1.0.0",
+					secrets, Dependency{Name: "vault", Ref: "registry.example/acme/vault", Version: "*"})
+				installTestProvider(t, "secrets", "1.2.0", secretsRef+":v1.2.0")
+				installTestProvider(t, "vault", "1.0.0", "registry.example/acme/vault:v1.0.0",
+					Dependency{Name: "secrets", Ref: secretsRef, Version: ">=1.3.0"})
+			},
+			wantErr: "dependency conflict: vault requires secrets >=1.3.0, but deploy requires ^1.2 (resolved 1.2.0)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("THIN_DATA_HOME", t.TempDir())
+			tt.install(t)
+			secretsManifest, _ := os.ReadFile(filepath.Join(DataHome(), "providers", "secrets", "thin.provider.yaml"))
+
+			got, err := InstallDependencies(context.Background(), "deploy")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("InstallDependencies error = %v, want %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("InstallDependencies error: %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("InstallDependencies = %+v, want %+v", got, tt.want)
+				}
+			}
+
+			// An installed dependency is never replaced
+			after, _ := os.ReadFile(filepath.Join(DataHome(), "providers", "secrets", "thin.provider.yaml"))
+			if string(after) != string(secretsManifest) {
+				t.Errorf("secrets manifest changed:\n%s\nwant:\n%s", after, secretsManifest)
+			}
+		})
+	}
+}
+
+func TestInstallDependenciesMismatch(t *testing.T) {
+	SetMessageOutput(io.Discard)
+	defer SetMessageOutput(os.Stdout)
+	t.Setenv("THIN_DATA_HOME", t.TempDir())
+
+	installTestProvider(t, "deploy", "1.0.0", "registry.example/acme/deploy:v1.0.0",
+		Dependency{Name: "secrets", Ref: "registry.example/acme/secrets", Version: "^1.2"})
+	installTestProvider(t, "secrets", "1.2.0", "registry.example/other/secrets:v1.2.0")
+
+	_, err := InstallDependencies(context.Background(), "deploy")
+	var mismatch *ProviderMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("InstallDependencies error = %v, want a ProviderMismatchError", err)
+	}
+	if mismatch.Installed != "registry.example/other/secrets:v1.2.0" {
+		t.Errorf("mismatch.Installed = %q, want the receipt source", mismatch.Installed)
+	}
+}
@@ -0,0 +1,210 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ProgressEvent is one line of --progress=json output. Fields not relevant
+// to an event are omitted; new fields may be added but existing ones keep
+// their meaning.
+type ProgressEvent struct {
+	Event      string    `json:"event"` // start, progress, done, skip, message or summary
+	Time       time.Time `json:"time"`
+	Digest     string    `json:"digest,omitempty"`
+	MediaType  string    `json:"mediaType,omitempty"`
+	Size       int64     `json:"size,omitempty"`
+	Bytes      int64     `json:"bytes,omitempty"`
+	DurationMs int64     `json:"durationMs,omitempty"`
+	Message    string    `json:"message,omitempty"`
+
+	// Summary totals
+	Downloaded      int   `json:"downloaded,omitempty"`
+	DownloadedBytes int64 `json:"downloadedBytes,omitempty"`
+	Cached          int   `json:"cached,omitempty"`
+	CachedBytes     int64 `json:"cachedBytes,omitempty"`
+}
+
+// eventWriter writes ProgressEvents as newline-delimited json. Text written
+// to it is turned into message events, one per line.
+type eventWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	buf []byte
+}
+
+func newEventWriter(w io.Writer) *eventWriter {
+	if ew, ok := w.(*eventWriter); ok {
+		return ew
+	}
+	return &eventWriter{w: w}
+}
+
+func (e *eventWriter) Write(p []byte) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.buf = append(e.buf, p...)
+	for {
+		i := bytes.IndexByte(e.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(bytes.TrimSpace(e.buf[:i]))
+		e.buf = e.buf[i+1:]
+		if line == "" {
+			continue
+		}
+		if err := e.write(&ProgressEvent{Event: "message", Message: line}); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Emit writes a single event
+func (e *eventWriter) Emit(ev *ProgressEvent) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.write(ev)
+}
+
+func (e *eventWriter) write(ev *ProgressEvent) error {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
+
+// progressEventInterval limits how often progress events are emitted per blob
+const progressEventInterval = 200 * time.Millisecond
+
+// JSONStatusHandler reports pull progress as newline-delimited json events
+type JSONStatusHandler struct {
+	mu        sync.Mutex
+	out       *eventWriter
+	progress  map[string]*NodeProgress
+	stats     pullStats
+	closeOnce sync.Once
+}
+
+// NewJSONStatusHandler creates a handler that writes events to w
+func NewJSONStatusHandler(w io.Writer) *JSONStatusHandler {
+	return &JSONStatusHandler{
+		out:      newEventWriter(w),
+		progress: make(map[string]*NodeProgress),
+		stats:    pullStats{start: time.Now()},
+	}
+}
+
+func (h *JSONStatusHandler) OnNodeDownloading(desc ocispec.Descriptor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.progress[desc.Digest.String()] = &NodeProgress{
+		Descriptor:    desc,
+		Status:        "Downloading",
+		StartTime:     now,
+		LastSpeedTime: now,
+	}
+	h.out.Emit(&ProgressEvent{Event: "start", Digest: desc.Digest.String(), MediaType: desc.MediaType, Size: desc.Size})
+}
+
+func (h *JSONStatusHandler) OnNodeDownloaded(desc ocispec.Descriptor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p, ok := h.progress[desc.Digest.String()]
+	if !ok {
+		return
+	}
+	p.Status = "Downloaded"
+	p.EndTime = time.Now()
+	p.BytesRead = desc.Size
+	h.stats.addDownloaded(desc.Size)
+	h.out.Emit(&ProgressEvent{
+		Event:      "done",
+		Digest:     desc.Digest.String(),
+		Size:       desc.Size,
+		Bytes:      desc.Size,
+		DurationMs: p.EndTime.Sub(p.StartTime).Milliseconds(),
+	})
+}
+
+func (h *JSONStatusHandler) OnNodeProcessing(desc ocispec.Descriptor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if p, ok := h.progress[desc.Digest.String()]; ok {
+		p.Status = "Processing"
+	}
+}
+
+func (h *JSONStatusHandler) OnNodeRestored(desc ocispec.Descriptor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if p, ok := h.progress[desc.Digest.String()]; ok {
+		p.Status = "Restored"
+		p.EndTime = time.Now()
+	}
+}
+
+func (h *JSONStatusHandler) OnNodeSkipped(desc ocispec.Descriptor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stats.addCached(desc.Size)
+	h.out.Emit(&ProgressEvent{Event: "skip", Digest: desc.Digest.String(), MediaType: desc.MediaType, Size: desc.Size})
+}
+
+func (h *JSONStatusHandler) UpdateProgress(digest string, bytesRead int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p, ok := h.progress[digest]
+	if !ok {
+		return
+	}
+	p.BytesRead = bytesRead
+	if time.Since(p.LastSpeedTime) < progressEventInterval && bytesRead < p.Descriptor.Size {
+		return
+	}
+	p.LastSpeedTime = time.Now()
+	p.LastSpeedRead = bytesRead
+	h.out.Emit(&ProgressEvent{Event: "progress", Digest: digest, Size: p.Descriptor.Size, Bytes: bytesRead})
+}
+
+func (h *JSONStatusHandler) Message(format string, args ...interface{}) {
+	h.out.Emit(&ProgressEvent{Event: "message", Message: fmt.Sprintf(format, args...)})
+}
+
+func (h *JSONStatusHandler) Close() {
+	h.closeOnce.Do(func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.stats.downloaded == 0 && h.stats.cached == 0 {
+			return
+		}
+		h.out.Emit(&ProgressEvent{
+			Event:           "summary",
+			DurationMs:      time.Since(h.stats.start).Milliseconds(),
+			Downloaded:      h.stats.downloaded,
+			DownloadedBytes: h.stats.downloadedBytes,
+			Cached:          h.stats.cached,
+			CachedBytes:     h.stats.cachedBytes,
+		})
+	})
+}
@@ -1,29 +1,38 @@
 package runtime
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ResolveTool finds the tool binary for name among the active providers
 func ResolveTool(name string) (string, error) {
-	dir, err := ActiveProviderToolsDir()
+	route, err := RouteCommand(name)
 	if err != nil {
+		if errors.Is(err, ErrNoRoute) {
+			return "", errors.New("tool not found: " + name)
+		}
 		return "", err
 	}
-
-	path := filepath.Join(dir, name)
-	info, err := os.Stat(path)
-	if err != nil || info.IsDir() {
+	if route.ToolPath == "" {
 		return "", errors.New("tool not found: " + name)
 	}
-	return path, nil
+	return route.ToolPath, nil
 }
 
 func ResolveToolWithProvider(name string, provider *ProviderRef) (string, error) {
+	if !isToolName(name) {
+		return "", errors.New("invalid tool name: " + name)
+	}
 	dir := filepath.Join(
-		ThinHome(),
+		DataHome(),
 		"providers",
 		provider.Namespace,
 		provider.Name,
@@ -39,13 +48,146 @@ func ResolveToolWithProvider(name string, provider *ProviderRef) (string, error)
 	return path, nil
 }
 
-func ExecTool(path string, args []string) error {
-	cmd := exec.Command(path, args...)
+// ResolveEntrypoint returns the path of the provider's entrypoint binary:
+// bin/<entrypoint.executable>, falling back to the platform-specific layouts
+func ResolveEntrypoint(providerDir string, manifest *ProviderManifest) (string, error) {
+	binaryPath := EntrypointCandidates(providerDir, manifest)[0]
+	if _, err := os.Stat(binaryPath); err != nil {
+		// Try alternate location for multi-platform
+		binaryPath, err = GetPlatformBinaryPath(providerDir)
+		if err != nil {
+			return "", fmt.Errorf("binary not found: %w", err)
+		}
+	}
+	return binaryPath, nil
+}
+
+// EntrypointCandidates returns the paths ResolveEntrypoint tries, in order
+func EntrypointCandidates(providerDir string, manifest *ProviderManifest) []string {
+	entrypoint := manifest.Entrypoint.Executable
+	if entrypoint == "" {
+		entrypoint = "entrypoint"
+	}
+
+	candidates := []string{filepath.Join(providerDir, "bin", entrypoint)}
+	for _, path := range platformBinaryCandidates(providerDir) {
+		if path != candidates[0] {
+			candidates = append(candidates, path)
+		}
+	}
+	return candidates
+}
+
+// ResolveExecutable returns the path of bin/<executable> in the provider,
+// used for capabilities that declare their own entrypoint
+func ResolveExecutable(providerDir, executable string) (string, error) {
+	binaryPath := filepath.Join(providerDir, "bin", filepath.FromSlash(executable))
+	if _, err := os.Stat(binaryPath); err != nil {
+		return "", fmt.Errorf("binary not found: %w", err)
+	}
+	return binaryPath, nil
+}
+
+// ExecOptions adjusts how a single provider process is run
+type ExecOptions struct {
+	Dir     string        // working directory; empty inherits thin's
+	Env     []string      // NAME=value entries set last, overriding everything else
+	Timeout time.Duration // kill the process after this long; zero means no limit
+}
+
+type execOptionsKey struct{}
+
+// WithExecOptions returns a context that runs provider processes with opts
+func WithExecOptions(ctx context.Context, opts ExecOptions) context.Context {
+	return context.WithValue(ctx, execOptionsKey{}, opts)
+}
+
+// ExecOptionsFrom returns the exec options carried by ctx
+func ExecOptionsFrom(ctx context.Context) ExecOptions {
+	opts, _ := ctx.Value(execOptionsKey{}).(ExecOptions)
+	return opts
+}
+
+// withTimeout applies the timeout to ctx
+func (o ExecOptions) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, o.Timeout)
+}
+
+// contextError describes why a process was stopped early
+func (o ExecOptions) contextError(ctx context.Context) error {
+	if sig, ok := signalCause(ctx); ok {
+		return &SignalError{Signal: sig}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && o.Timeout > 0 {
+		return fmt.Errorf("timed out after %s: %w", o.Timeout, ctx.Err())
+	}
+	return ctx.Err()
+}
+
+// waitDelay is how long a provider process gets to exit after it has been
+// handed a signal, or after a timeout, before it is killed
+const waitDelay = 10 * time.Second
+
+// ExecTool runs the tool at path, passing the current trace context through
+// TRACEPARENT so provider spans nest under thin's. env entries are added to
+// the inherited environment. ExecOptions in ctx set the working directory,
+// extra variables and a timeout.
+func ExecTool(ctx context.Context, path string, args []string, env ...string) (err error) {
+	ctx, span := StartSpan(ctx, "thin.exec",
+		attribute.String("thin.exec.path", path),
+		attribute.Int("thin.exec.args.count", len(args)),
+	)
+	defer func() { EndSpan(span, err) }()
+
+	opts := ExecOptionsFrom(ctx)
+	ctx, cancel := opts.withTimeout(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir = opts.Dir
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	cmd.Env = append(os.Environ(),
-		"THIN_HOME="+ThinHome(),
-	)
-	return cmd.Run()
+	cmd.Env = append(os.Environ(), ExecEnv(ctx, env...)...)
+	cmd.WaitDelay = waitDelay
+	// With a deadline the provider runs in a process group of its own, so
+	// that everything it started can be killed when the deadline passes
+	_, group := ctx.Deadline()
+	release, err := startProcess(ctx, cmd, group)
+	if err == nil {
+		err = cmd.Wait()
+		release()
+	}
+	if cmd.ProcessState != nil {
+		span.SetAttributes(attribute.Int("thin.exec.exit_code", cmd.ProcessState.ExitCode()))
+	}
+	if ctx.Err() != nil {
+		return opts.contextError(ctx)
+	}
+	return err
+}
+
+// ExecEnv returns the variables a provider process gets on top of the
+// inherited environment: thin's own, then env, then those from the exec
+// options in ctx
+func ExecEnv(ctx context.Context, env ...string) []string {
+	return append(append(thinEnv(ctx), env...), ExecOptionsFrom(ctx).Env...)
+}
+
+// thinEnv returns the variables thin sets for every provider process:
+// its storage locations, the project root and the trace context
+func thinEnv(ctx context.Context) []string {
+	env := []string{
+		"THIN_HOME=" + DataHome(),
+		"THIN_DATA_HOME=" + DataHome(),
+		"THIN_CACHE_HOME=" + CacheHome(),
+		"THIN_CONFIG_HOME=" + ConfigHome(),
+	}
+	if root := ProjectRoot(); root != "" {
+		env = append(env, "THIN_PROJECT_ROOT="+root)
+	}
+	return append(env, TraceEnv(ctx)...)
 }
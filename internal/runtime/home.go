@@ -5,14 +5,97 @@ import (
 	"path/filepath"
 )
 
-func ThinHome() string {
-	if v := os.Getenv("THIN_HOME"); v != "" {
+// thin keeps its state in four separate locations:
+//
+//	data     installed providers    THIN_DATA_HOME, $XDG_DATA_HOME/thin
+//	cache    compiled WASI modules  THIN_CACHE_HOME, $XDG_CACHE_HOME/thin
+//	config   user config.yaml       THIN_CONFIG_HOME, $XDG_CONFIG_HOME/thin
+//	context  active-provider.yaml   <project root>/.thin, else the config home
+//
+// THIN_HOME, when set, is the base for data, cache (THIN_HOME/cache) and config.
+// Without any overrides everything lives under ~/.thin, as in earlier releases.
+
+// DataHome returns the directory holding installed providers
+func DataHome() string {
+	return homeDir("THIN_DATA_HOME", "XDG_DATA_HOME", "")
+}
+
+// CacheHome returns the directory holding data thin can regenerate, such as
+// compiled WASI modules
+func CacheHome() string {
+	return homeDir("THIN_CACHE_HOME", "XDG_CACHE_HOME", "cache")
+}
+
+// ConfigHome returns the directory holding user-level configuration
+func ConfigHome() string {
+	return homeDir("THIN_CONFIG_HOME", "XDG_CONFIG_HOME", "")
+}
+
+// ContextDir returns the directory holding the project context
+// (active-provider.yaml): .thin in the project root, or the config home
+// when thin is not run inside a project
+func ContextDir() string {
+	if root := ProjectRoot(); root != "" {
+		return filepath.Join(root, ".thin")
+	}
+	return ConfigHome()
+}
+
+// homeDir resolves a location from its THIN_* override, THIN_HOME, its XDG
+// base directory, or ~/.thin, in that order. sub is appended below THIN_HOME
+// and ~/.thin so that locations sharing the legacy home don't collide.
+func homeDir(thinVar, xdgVar, sub string) string {
+	if v := os.Getenv(thinVar); v != "" {
 		return v
 	}
-	if _, err := os.Stat(".thin"); err == nil {
-		wd, _ := os.Getwd()
-		return filepath.Join(wd, ".thin")
+	if v := os.Getenv("THIN_HOME"); v != "" {
+		return filepath.Join(v, sub)
+	}
+	if v := os.Getenv(xdgVar); v != "" {
+		return filepath.Join(v, "thin")
 	}
 	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".thin")
+	return filepath.Join(home, ".thin", sub)
+}
+
+// ProjectRoot returns the nearest directory at or above the working directory
+// that contains a .thin directory or a thin.yaml file, the way git finds .git.
+// The search stops at the repository root (a directory containing .git) and at
+// the filesystem root. The user's home directory is not a project: its .thin
+// is the legacy global store.
+// Returns an empty string when thin is not run inside a project.
+func ProjectRoot() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	home, _ := os.UserHomeDir()
+
+	for dir := wd; ; {
+		if dir != home && isProjectDir(dir) {
+			return dir
+		}
+
+		// Don't escape the enclosing repository
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// isProjectDir reports whether dir holds a thin project marker
+func isProjectDir(dir string) bool {
+	if stat, err := os.Stat(filepath.Join(dir, ".thin")); err == nil && stat.IsDir() {
+		return true
+	}
+	if stat, err := os.Stat(filepath.Join(dir, "thin.yaml")); err == nil && !stat.IsDir() {
+		return true
+	}
+	return false
 }
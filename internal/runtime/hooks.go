@@ -0,0 +1,152 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	gruntime "runtime"
+	"strconv"
+
+	"github.com/sourceplane/thin/internal/manifest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Hook phases
+const (
+	HookPre  = "pre"
+	HookPost = "post"
+)
+
+// Hook is a command run before or after matching provider invocations
+type Hook struct {
+	// Provider matches the provider name, namespace/name or full reference;
+	// glob patterns are allowed and empty matches every provider
+	Provider string `yaml:"provider"`
+
+	// Capability matches the first argument passed to the provider (glob
+	// patterns allowed, empty matches everything)
+	Capability string `yaml:"capability"`
+
+	// Run is a command line executed by the system shell
+	Run string `yaml:"run"`
+}
+
+// Hooks holds the hooks of each phase, run in declaration order
+type Hooks struct {
+	Pre  []Hook `yaml:"pre"`
+	Post []Hook `yaml:"post"`
+}
+
+// HookContext describes the provider invocation a hook runs around
+type HookContext struct {
+	Provider   *ProviderRef
+	Capability string
+	Args       []string
+	ExitCode   int // Provider exit code; only set for post hooks
+}
+
+// Matches reports whether the hook applies to an invocation
+func (h *Hook) Matches(hc *HookContext) bool {
+	if h.Provider != "" {
+		candidates := []string{hc.Provider.Name, hc.Provider.String()}
+		if hc.Provider.Namespace != "" {
+			candidates = append(candidates, hc.Provider.Namespace+"/"+hc.Provider.Name)
+		}
+		matched := false
+		for _, c := range candidates {
+			if ok, _ := path.Match(h.Provider, c); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if h.Capability != "" {
+		ok, _ := path.Match(h.Capability, hc.Capability)
+		return ok
+	}
+	return true
+}
+
+// HookError is returned when a hook command fails
+type HookError struct {
+	Phase string
+	Hook  Hook
+	Err   error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook %q failed: %v", e.Phase, e.Hook.Run, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// RunHooks runs every hook in hooks that matches hc, stopping at the first
+// failure. Hooks get thin's environment plus THIN_HOOK, THIN_HOOK_PROVIDER,
+// THIN_HOOK_PROVIDER_NAME, THIN_HOOK_CAPABILITY, THIN_HOOK_ARGS and, for
+// post hooks, THIN_HOOK_EXIT_CODE.
+func RunHooks(ctx context.Context, phase string, hooks []Hook, hc *HookContext) error {
+	for _, hook := range hooks {
+		if !hook.Matches(hc) {
+			continue
+		}
+		if err := runHook(ctx, phase, hook, hc); err != nil {
+			return &HookError{Phase: phase, Hook: hook, Err: err}
+		}
+	}
+	return nil
+}
+
+func runHook(ctx context.Context, phase string, hook Hook, hc *HookContext) (err error) {
+	ctx, span := StartSpan(ctx, "thin.hook",
+		attribute.String("thin.hook.phase", phase),
+		attribute.String("thin.hook.run", hook.Run),
+	)
+	defer func() { EndSpan(span, err) }()
+
+	var cmd *exec.Cmd
+	if gruntime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", hook.Run)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", hook.Run)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), thinEnv(ctx)...)
+	cmd.Env = append(cmd.Env,
+		"THIN_HOOK="+phase,
+		"THIN_HOOK_PROVIDER="+hc.Provider.String(),
+		"THIN_HOOK_PROVIDER_NAME="+hc.Provider.Name,
+		"THIN_HOOK_CAPABILITY="+hc.Capability,
+		"THIN_HOOK_ARGS="+manifest.JoinArgs(hc.Args),
+	)
+	if phase == HookPost {
+		cmd.Env = append(cmd.Env, "THIN_HOOK_EXIT_CODE="+strconv.Itoa(hc.ExitCode))
+	}
+	return cmd.Run()
+}
+
+// ExitCode returns the process exit code that err from ExecTool or ExecWASI
+// stands for
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	var wasiErr *WASIExitError
+	if errors.As(err, &wasiErr) {
+		return int(wasiErr.Code)
+	}
+	return 1
+}
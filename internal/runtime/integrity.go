@@ -0,0 +1,178 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Prefixes of the entries hashTree records for files that aren't regular
+const (
+	linkPrefix        = "link:"        // followed by the symlink target
+	unsupportedPrefix = "unsupported:" // followed by the file type
+)
+
+// hashTree returns the sha256 of every regular file under dir, keyed by
+// slash-separated path relative to dir. Symlinks are recorded as
+// "link:<target>" and other files that aren't directories as
+// "unsupported:<type>", which diffTrees never accepts. Paths for which skip
+// returns true are left out.
+func hashTree(dir string, skip func(rel string) bool) (map[string]string, error) {
+	sums := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if skip != nil && skip(rel) {
+			return nil
+		}
+
+		switch {
+		case d.Type().IsRegular():
+			sum, err := hashFile(path)
+			if err != nil {
+				return err
+			}
+			sums[rel] = sum
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			sums[rel] = linkPrefix + filepath.ToSlash(target)
+		default:
+			sums[rel] = unsupportedPrefix + d.Type().String()
+		}
+		return nil
+	})
+	return sums, err
+}
+
+// hashFile returns the hex-encoded sha256 of the file at path
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// TreeDiff lists the differences between a recorded and a current file tree
+type TreeDiff struct {
+	Added    []string `json:"added,omitempty" yaml:"added,omitempty"`
+	Missing  []string `json:"missing,omitempty" yaml:"missing,omitempty"`
+	Modified []string `json:"modified,omitempty" yaml:"modified,omitempty"`
+}
+
+// Empty reports whether the trees were identical
+func (d *TreeDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Missing) == 0 && len(d.Modified) == 0
+}
+
+// String summarises the diff as "modified: a; missing: b; added: c"
+func (d *TreeDiff) String() string {
+	var parts []string
+	if len(d.Modified) > 0 {
+		parts = append(parts, "modified: "+strings.Join(d.Modified, ", "))
+	}
+	if len(d.Missing) > 0 {
+		parts = append(parts, "missing: "+strings.Join(d.Missing, ", "))
+	}
+	if len(d.Added) > 0 {
+		parts = append(parts, "added: "+strings.Join(d.Added, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// diffTrees compares recorded checksums against current ones. A file of an
+// unsupported type is always modified, even if it was recorded as such.
+func diffTrees(recorded, current map[string]string) *TreeDiff {
+	diff := &TreeDiff{}
+	for path, sum := range recorded {
+		got, ok := current[path]
+		switch {
+		case !ok:
+			diff.Missing = append(diff.Missing, path)
+		case got != sum, strings.HasPrefix(got, unsupportedPrefix):
+			diff.Modified = append(diff.Modified, path)
+		}
+	}
+	for path := range current {
+		if _, ok := recorded[path]; !ok {
+			diff.Added = append(diff.Added, path)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Missing)
+	sort.Strings(diff.Modified)
+	return diff
+}
+
+// setTreeWritable adds or removes write permission on dir and everything
+// below it. Directories are made writable before being walked into and
+// sealed after, so the walk works in both directions.
+func setTreeWritable(dir string, writable bool) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+
+	var dirs []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if writable {
+				return os.Chmod(path, info.Mode().Perm()|0200)
+			}
+			dirs = append(dirs, path)
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if writable {
+			return os.Chmod(path, info.Mode().Perm()|0200)
+		}
+		return os.Chmod(path, info.Mode().Perm()&^0222)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Seal directories deepest first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		info, err := os.Stat(dirs[i])
+		if err != nil {
+			return err
+		}
+		if err := os.Chmod(dirs[i], info.Mode().Perm()&^0222); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHashTreeSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tool"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("tool", filepath.Join(dir, "alias")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	recorded, err := hashTree(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := recorded["alias"]; got != "link:tool" {
+		t.Errorf("hashTree recorded alias as %q, want %q", got, "link:tool")
+	}
+
+	// Retargeting the link is a modification
+	os.Remove(filepath.Join(dir, "alias"))
+	if err := os.Symlink("/bin/sh", filepath.Join(dir, "alias")); err != nil {
+		t.Fatal(err)
+	}
+	current, err := hashTree(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := diffTrees(recorded, current); !reflect.DeepEqual(diff.Modified, []string{"alias"}) {
+		t.Errorf("diffTrees after retargeting = %+v, want alias modified", diff)
+	}
+}
+
+func TestDiffTrees(t *testing.T) {
+	tests := []struct {
+		name              string
+		recorded, current map[string]string
+		want              TreeDiff
+	}{
+		{
+			name:     "identical",
+			recorded: map[string]string{"a": "1", "b/c": "2", "l": "link:a"},
+			current:  map[string]string{"a": "1", "b/c": "2", "l": "link:a"},
+		},
+		{
+			name:     "changes",
+			recorded: map[string]string{"a": "1", "b": "2", "c": "3"},
+			current:  map[string]string{"a": "1", "b": "changed", "d": "4", "e": "5"},
+			want:     TreeDiff{Added: []string{"d", "e"}, Missing: []string{"c"}, Modified: []string{"b"}},
+		},
+		{
+			name:     "file replaced by symlink",
+			recorded: map[string]string{"a": "1"},
+			current:  map[string]string{"a": "link:/etc/passwd"},
+			want:     TreeDiff{Modified: []string{"a"}},
+		},
+		{
+			name:     "unsupported file type",
+			recorded: map[string]string{"a": "unsupported:p---------"},
+			current:  map[string]string{"a": "unsupported:p---------"},
+			want:     TreeDiff{Modified: []string{"a"}},
+		},
+		{
+			name:     "unsupported file added",
+			recorded: map[string]string{},
+			current:  map[string]string{"fifo": "unsupported:p---------"},
+			want:     TreeDiff{Added: []string{"fifo"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffTrees(tt.recorded, tt.current)
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("diffTrees = %+v, want %+v", *got, tt.want)
+			}
+			if got.Empty() != (tt.want.Added == nil && tt.want.Missing == nil && tt.want.Modified == nil) {
+				t.Errorf("Empty() = %v for %+v", got.Empty(), *got)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sourceplane/thin/internal/manifest"
+	"gopkg.in/yaml.v3"
+)
+
+// Valid values for capability input/output types and lifecycle stability
+var (
+	validInputTypes = []string{"string", "number", "integer", "boolean", "array", "object"}
+	validStability  = []string{"stable", "experimental", "deprecated"}
+)
+
+// LintProblem is a manifest issue at a position in the source file.
+// Line and Column are 1-based; zero means unknown.
+type LintProblem struct {
+	Line     int    `json:"line" yaml:"line"`
+	Column   int    `json:"column" yaml:"column"`
+	Severity string `json:"severity" yaml:"severity"` // "error" or "warning"
+	Message  string `json:"message" yaml:"message"`
+}
+
+// LintResult is the outcome of linting one manifest
+type LintResult struct {
+	File     string        `json:"file" yaml:"file"`
+	Problems []LintProblem `json:"problems" yaml:"problems"`
+}
+
+// Errors returns the number of error-severity problems
+func (r *LintResult) Errors() int {
+	n := 0
+	for _, p := range r.Problems {
+		if p.Severity == "error" {
+			n++
+		}
+	}
+	return n
+}
+
+// Format renders a problem as file:line:column: severity: message
+func (r *LintResult) Format(p LintProblem) string {
+	switch {
+	case p.Line > 0 && p.Column > 0:
+		return fmt.Sprintf("%s:%d:%d: %s: %s", r.File, p.Line, p.Column, p.Severity, p.Message)
+	case p.Line > 0:
+		return fmt.Sprintf("%s:%d: %s: %s", r.File, p.Line, p.Severity, p.Message)
+	default:
+		return fmt.Sprintf("%s: %s: %s", r.File, p.Severity, p.Message)
+	}
+}
+
+// LintManifest strictly checks a provider manifest. path is either a
+// thin.provider.yaml file or a package directory containing one; platform
+// binaries are looked up relative to the package directory.
+func LintManifest(path string) (*LintResult, error) {
+	file, pkgDir := path, filepath.Dir(path)
+	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+		file, pkgDir = filepath.Join(path, "thin.provider.yaml"), path
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	l := &linter{result: &LintResult{File: file}}
+	l.lint(data, pkgDir)
+
+	sort.SliceStable(l.result.Problems, func(i, j int) bool {
+		a, b := l.result.Problems[i], l.result.Problems[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.result, nil
+}
+
+type linter struct {
+	result *LintResult
+	doc    *yaml.Node
+}
+
+func (l *linter) errorAt(node *yaml.Node, format string, args ...interface{}) {
+	l.add(node, "error", format, args...)
+}
+
+func (l *linter) warningAt(node *yaml.Node, format string, args ...interface{}) {
+	l.add(node, "warning", format, args...)
+}
+
+func (l *linter) add(node *yaml.Node, severity, format string, args ...interface{}) {
+	p := LintProblem{Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		p.Line, p.Column = node.Line, node.Column
+	}
+	l.result.Problems = append(l.result.Problems, p)
+}
+
+func (l *linter) lint(data []byte, pkgDir string) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		line, msg := splitYAMLError(err.Error())
+		l.result.Problems = append(l.result.Problems, LintProblem{Line: line, Severity: "error", Message: msg})
+		return
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		l.errorAt(&root, "manifest must be a YAML mapping")
+		return
+	}
+	l.doc = root.Content[0]
+
+	// Each apiVersion has its own set of keys
+	var header struct {
+		APIVersion string `yaml:"apiVersion"`
+	}
+	_ = l.doc.Decode(&header)
+	wire, err := manifest.WireType(header.APIVersion)
+	if err != nil {
+		l.errorAt(l.nodeAt("apiVersion"), "%v", err)
+		return
+	}
+
+	// Unknown keys are silently dropped by the regular decoder
+	l.checkKnownFields(l.doc, wire, "")
+
+	provider, err := manifest.DecodeNode(l.doc)
+	if err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			l.errorAt(l.doc, "%v", err)
+			return
+		}
+		for _, e := range typeErr.Errors {
+			line, msg := splitYAMLError(e)
+			l.result.Problems = append(l.result.Problems, LintProblem{Line: line, Severity: "error", Message: msg})
+		}
+	}
+	m := &ProviderManifest{Provider: *provider}
+
+	for _, p := range m.problems() {
+		l.errorAt(l.nodeAt(p.Path...), "%v", p.Err)
+	}
+
+	l.checkDefaultArgs(m)
+	l.checkCapabilities(m)
+	l.checkPlatforms(m, pkgDir)
+	l.checkCapabilityExecutables(m, pkgDir)
+}
+
+// checkDefaultArgs reports defaultArgs lines that can't be split into words
+func (l *linter) checkDefaultArgs(m *ProviderManifest) {
+	args := m.Entrypoint.DefaultArgs
+	if args.IsList() || args.Line == "" {
+		return
+	}
+	if _, err := manifest.SplitTemplateArgs(args.Line); err != nil {
+		l.errorAt(l.nodeAt("entrypoint", "defaultArgs"), "entrypoint.defaultArgs: %v", err)
+	}
+}
+
+// checkCapabilities validates input/output types and lifecycle stability
+func (l *linter) checkCapabilities(m *ProviderManifest) {
+	for name, capability := range m.Capabilities {
+		if s := capability.Lifecycle.Stability; s != "" && !containsString(validStability, s) {
+			l.errorAt(l.nodeAt("capabilities", name, "lifecycle", "stability"),
+				"capability %s: invalid lifecycle stability %q (expected one of: %s)", name, s, strings.Join(validStability, ", "))
+		}
+
+		seen := map[string]bool{}
+		for i, input := range capability.Inputs {
+			if input.Name == "" {
+				l.errorAt(l.nodeAt("capabilities", name, "inputs", i), "capability %s: inputs[%d] is missing a name", name, i)
+			} else if seen[input.Name] {
+				l.errorAt(l.nodeAt("capabilities", name, "inputs", i, "name"), "capability %s: duplicate input %q", name, input.Name)
+			}
+			seen[input.Name] = true
+
+			if input.Type == "" {
+				l.warningAt(l.nodeAt("capabilities", name, "inputs", i), "capability %s: input %q has no type", name, input.Name)
+			} else if !containsString(validInputTypes, input.Type) {
+				l.errorAt(l.nodeAt("capabilities", name, "inputs", i, "type"),
+					"capability %s: input %q has invalid type %q (expected one of: %s)", name, input.Name, input.Type, strings.Join(validInputTypes, ", "))
+			}
+		}
+
+		for i, output := range capability.Outputs {
+			if output.Type != "" && !containsString(validInputTypes, output.Type) {
+				l.errorAt(l.nodeAt("capabilities", name, "outputs", i, "type"),
+					"capability %s: output %q has invalid type %q (expected one of: %s)", name, output.Name, output.Type, strings.Join(validInputTypes, ", "))
+			}
+		}
+	}
+}
+
+// checkPlatforms verifies every declared platform binary exists in the package
+func (l *linter) checkPlatforms(m *ProviderManifest, pkgDir string) {
+	for i, p := range m.Platforms {
+		if p.OS == "" || p.Arch == "" {
+			l.errorAt(l.nodeAt("platforms", i), "platforms[%d] must set both os and arch", i)
+		}
+		if p.Binary == "" {
+			l.errorAt(l.nodeAt("platforms", i), "platforms[%d] is missing a binary", i)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(pkgDir, filepath.FromSlash(p.Binary))); err != nil {
+			l.errorAt(l.nodeAt("platforms", i, "binary"), "binary for %s/%s not found in package: %s", p.OS, p.Arch, p.Binary)
+		}
+	}
+}
+
+// checkCapabilityExecutables verifies dedicated capability executables exist
+// in the package
+func (l *linter) checkCapabilityExecutables(m *ProviderManifest, pkgDir string) {
+	for name := range m.Capabilities {
+		ep := m.CapabilityEntrypoint(name)
+		if ep == nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(pkgDir, "bin", filepath.FromSlash(ep.Executable))); err != nil {
+			l.errorAt(l.nodeAt("capabilities", name, "entrypoint", "executable"),
+				"capability %s: executable not found in package: bin/%s", name, ep.Executable)
+		}
+	}
+}
+
+// checkKnownFields reports mapping keys that don't correspond to a field of t
+func (l *linter) checkKnownFields(node *yaml.Node, t reflect.Type, path string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// Types that decode themselves accept their own shapes
+	if reflect.PtrTo(t).Implements(reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				where := "at top level"
+				if path != "" {
+					where = "in " + path
+				}
+				msg := fmt.Sprintf("unknown field %q %s", key.Value, where)
+				if suggestion := closestName(key.Value, fields); suggestion != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				l.errorAt(key, "%s", msg)
+				continue
+			}
+			l.checkKnownFields(value, field.Type, joinYAMLPath(path, key.Value))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			l.checkKnownFields(node.Content[i+1], t.Elem(), joinYAMLPath(path, node.Content[i].Value))
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			l.checkKnownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// nodeAt returns the value node at path, or the deepest existing ancestor
+func (l *linter) nodeAt(path ...interface{}) *yaml.Node {
+	node := l.doc
+	for _, step := range path {
+		var next *yaml.Node
+		switch key := step.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == key {
+						next = node.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && key < len(node.Content) {
+				next = node.Content[key]
+			}
+		}
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+// yamlFields maps yaml keys to struct fields, following inline fields
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("yaml")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			for k, v := range yamlFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// closestName suggests a known field within a small edit distance of name
+func closestName(name string, fields map[string]reflect.StructField) string {
+	best, bestDist := "", 3
+	for candidate := range fields {
+		d := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if d < bestDist || (d == bestDist && best != "" && candidate < best) {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// splitYAMLError extracts the line number from a yaml.v3 error message
+func splitYAMLError(msg string) (int, string) {
+	if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return line, m[2]
+	}
+	return 0, strings.TrimPrefix(msg, "yaml: ")
+}
+
+func joinYAMLPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
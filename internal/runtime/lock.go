@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockPollInterval is how often a waiting process retries a held lock
+const lockPollInterval = 100 * time.Millisecond
+
+// FileLock is an exclusive advisory lock on a lock file. It only excludes
+// other thin processes that take the same lock.
+type FileLock struct {
+	f *os.File
+}
+
+// AcquireLock takes an exclusive lock on path, creating the file if needed.
+// It waits until the lock is free or ctx is done. onWait, if not nil, is
+// called once when another process holds the lock.
+func AcquireLock(ctx context.Context, path string, onWait func()) (*FileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock %s: %w", path, err)
+	}
+
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			return &FileLock{f: f}, nil
+		}
+
+		if onWait != nil {
+			onWait()
+			onWait = nil
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, fmt.Errorf("gave up waiting for lock %s: %w", path, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// Unlock releases the lock. The lock file is left in place: removing it
+// would let two processes lock different files at the same path.
+func (l *FileLock) Unlock() error {
+	if err := unlockFile(l.f); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// lockPath returns the lock file for a named piece of state. Locks live
+// outside provider directories so they aren't part of install receipts.
+func lockPath(name string) string {
+	return filepath.Join(DataHome(), "locks", name+".lock")
+}
+
+// LockProvider serializes installs and removals of the named provider
+func LockProvider(ctx context.Context, name string) (*FileLock, error) {
+	if err := ValidateProviderName(name); err != nil {
+		return nil, err
+	}
+	return AcquireLock(ctx, lockPath("provider-"+name), func() {
+		fmt.Fprintf(messageOutput, "Waiting for another thin process to finish with provider %s...\n", name)
+	})
+}
+
+// lockActiveProviders serializes read-modify-write updates of
+// active-provider.yaml. One lock covers every project's file, so none is
+// left behind in a project's .thin directory.
+func lockActiveProviders() (*FileLock, error) {
+	return AcquireLock(context.Background(), lockPath("active-providers"), nil)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers see either the old or the new contents and
+// never a partial write
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Removing after a successful rename is a harmless no-op
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -5,82 +5,24 @@ import (
 	"os"
 	"path/filepath"
 
-	"gopkg.in/yaml.v3"
+	"github.com/sourceplane/thin/internal/manifest"
 )
 
-// ProviderManifest represents the thin.provider.yaml structure
-// Spec: https://github.com/sourceplane/thin/blob/main/oci/thin.provider.yaml
+// ProviderManifest is a parsed thin.provider.yaml. Manifests of every
+// supported apiVersion decode into the same internal representation.
 type ProviderManifest struct {
-	APIVersion string `yaml:"apiVersion"`
-	Kind       string `yaml:"kind"`
-
-	Metadata struct {
-		Name        string        `yaml:"name"`
-		Version     string        `yaml:"version"`
-		Description string        `yaml:"description"`
-		Homepage    string        `yaml:"homepage"`
-		Maintainers []interface{} `yaml:"maintainers"` // Can be complex
-		License     string        `yaml:"license"`
-	} `yaml:"metadata"`
-
-	Distribution struct {
-		Type string `yaml:"type"` // "oci"
-		Ref  string `yaml:"ref"`  // "ghcr.io/sourceplane/lite-ci"
-	} `yaml:"distribution"`
-
-	Runtime struct {
-		Default   string        `yaml:"default"`
-		Supported []interface{} `yaml:"supported"` // Runtime configurations
-	} `yaml:"runtime"`
-
-	Entrypoint struct {
-		Executable  string `yaml:"executable"`
-		DefaultArgs string `yaml:"defaultArgs"`
-	} `yaml:"entrypoint"`
-
-	Platforms []struct {
-		OS     string `yaml:"os"`
-		Arch   string `yaml:"arch"`
-		Binary string `yaml:"binary"`
-	} `yaml:"platforms"`
-
-	Layers map[string]interface{} `yaml:"layers"`
-
-	Capabilities map[string]struct {
-		Description string `yaml:"description"`
-		Lifecycle   struct {
-			Stability   string `yaml:"stability"` // stable, experimental, deprecated
-			IntroducedIn string `yaml:"introducedIn"`
-		} `yaml:"lifecycle"`
-		Inputs []struct {
-			Name        string      `yaml:"name"`
-			Type        string      `yaml:"type"`
-			Required    bool        `yaml:"required"`
-			Default     interface{} `yaml:"default"`
-			Description string      `yaml:"description"`
-		} `yaml:"inputs"`
-		Outputs []struct {
-			Name        string `yaml:"name"`
-			Type        string `yaml:"type"`
-			Description string `yaml:"description"`
-		} `yaml:"outputs"`
-	} `yaml:"capabilities"`
-
-	Assets struct {
-		Root          string        `yaml:"root"`
-		Contains      []string      `yaml:"contains"`
-		Immutability  interface{}   `yaml:"immutability"`
-	} `yaml:"assets"`
-
-	Architecture struct {
-		Pattern   string   `yaml:"pattern"`
-		Stages    []string `yaml:"stages"`
-		Principles []string `yaml:"principles"`
-	} `yaml:"architecture"`
-
-	Models map[string]interface{} `yaml:"models"`
+	manifest.Provider
 }
 
+// WASIRuntime configures how a wasi provider module is run
+type WASIRuntime = manifest.WASIRuntime
+
+// WASIPreopen maps a host directory into the module's filesystem
+type WASIPreopen = manifest.WASIPreopen
+
+// Dependency is another provider that this provider invokes at runtime
+type Dependency = manifest.Dependency
+
 // ReadProviderManifest reads and parses the thin.provider.yaml file
 // Returns error if manifest exists but is invalid
 // If manifest doesn't exist, returns nil (manifest is optional)
@@ -93,50 +35,108 @@ func ReadProviderManifest(providerDir string) (*ProviderManifest, error) {
 		return nil, nil
 	}
 
-	var manifest ProviderManifest
-	if err := yaml.Unmarshal(data, &manifest); err != nil {
-		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	provider, err := manifest.Decode(data)
+	if err != nil {
+		return nil, err
 	}
 
 	// Validate required fields if manifest was found
-	if err := manifest.Validate(); err != nil {
+	m := &ProviderManifest{Provider: *provider}
+	if err := m.Validate(); err != nil {
 		return nil, err
 	}
 
-	return &manifest, nil
+	return m, nil
 }
 
 // Validate checks that all required fields are present and valid
 func (m *ProviderManifest) Validate() error {
-	if m.APIVersion == "" {
-		return fmt.Errorf("manifest missing required field: apiVersion")
+	if problems := m.problems(); len(problems) > 0 {
+		return problems[0].Err
 	}
-	if m.APIVersion != "thin.io/v1" {
-		return fmt.Errorf("unsupported apiVersion: %s (expected: thin.io/v1)", m.APIVersion)
+	return nil
+}
+
+// manifestProblem is a validation failure and the YAML path of the field
+// it concerns (string keys and int indices)
+type manifestProblem struct {
+	Path []interface{}
+	Err  error
+}
+
+// problems returns every validation failure, in document order
+func (m *ProviderManifest) problems() []manifestProblem {
+	var problems []manifestProblem
+	add := func(err error, path ...interface{}) {
+		problems = append(problems, manifestProblem{Path: path, Err: err})
 	}
-	if m.Kind != "Provider" {
-		return fmt.Errorf("manifest kind must be 'Provider', got: %s", m.Kind)
+
+	if m.APIVersion == "" {
+		add(fmt.Errorf("manifest missing required field: apiVersion"))
+	} else if _, err := manifest.WireType(m.APIVersion); err != nil {
+		add(err, "apiVersion")
+	}
+	if m.Kind != manifest.Kind {
+		add(fmt.Errorf("manifest kind must be 'Provider', got: %s", m.Kind), "kind")
 	}
 	if m.Metadata.Name == "" {
-		return fmt.Errorf("manifest missing required field: metadata.name")
+		add(fmt.Errorf("manifest missing required field: metadata.name"), "metadata")
 	}
 	if m.Metadata.Version == "" {
-		return fmt.Errorf("manifest missing required field: metadata.version")
+		add(fmt.Errorf("manifest missing required field: metadata.version"), "metadata")
 	}
 	if m.Distribution.Type == "" {
-		return fmt.Errorf("manifest missing required field: distribution.type")
-	}
-	if m.Distribution.Type != "oci" {
-		return fmt.Errorf("unsupported distribution type: %s (expected: oci)", m.Distribution.Type)
+		add(fmt.Errorf("manifest missing required field: distribution.type"), "distribution")
+	} else if m.Distribution.Type != "oci" {
+		add(fmt.Errorf("unsupported distribution type: %s (expected: oci)", m.Distribution.Type), "distribution", "type")
 	}
 	if m.Distribution.Ref == "" {
-		return fmt.Errorf("manifest missing required field: distribution.ref")
+		add(fmt.Errorf("manifest missing required field: distribution.ref"), "distribution")
 	}
-	if m.Entrypoint.Executable == "" {
-		return fmt.Errorf("manifest missing required field: entrypoint.executable")
+	if m.Entrypoint.Executable == "" && m.Runtime.Default != RuntimeWASI && !m.capabilitiesHaveEntrypoints() {
+		add(fmt.Errorf("manifest missing required field: entrypoint.executable"), "entrypoint")
+	}
+	switch m.Runtime.Default {
+	case "", RuntimeNative, RuntimeWASI:
+	default:
+		add(fmt.Errorf("unsupported runtime.default: %s (expected: %s or %s)", m.Runtime.Default, RuntimeNative, RuntimeWASI), "runtime", "default")
 	}
 	if len(m.Capabilities) == 0 {
-		return fmt.Errorf("manifest must define at least one capability")
+		add(fmt.Errorf("manifest must define at least one capability"), "capabilities")
+	}
+	if m.Assets.Root != "" && !filepath.IsLocal(filepath.FromSlash(m.Assets.Root)) {
+		add(fmt.Errorf("assets.root must be a path inside the provider: %s", m.Assets.Root), "assets", "root")
+	}
+	for i, dep := range m.Dependencies {
+		if dep.Name == "" {
+			add(fmt.Errorf("manifest missing required field: dependencies[%d].name", i), "dependencies", i)
+		}
+		if dep.Ref == "" {
+			add(fmt.Errorf("manifest missing required field: dependencies[%d].ref", i), "dependencies", i)
+		}
+		if _, err := parseVersionConstraint(dep.Version); err != nil {
+			add(fmt.Errorf("dependencies[%d] (%s): %w", i, dep.Name, err), "dependencies", i, "version")
+		}
+	}
+	return problems
+}
+
+// capabilitiesHaveEntrypoints reports whether every capability declares its
+// own entrypoint, making the provider-level one optional
+func (m *ProviderManifest) capabilitiesHaveEntrypoints() bool {
+	for _, c := range m.Capabilities {
+		if c.Entrypoint == nil || c.Entrypoint.Executable == "" {
+			return false
+		}
+	}
+	return len(m.Capabilities) > 0
+}
+
+// CapabilityEntrypoint returns the dedicated entrypoint of a capability, or
+// nil if it runs through the provider's entrypoint
+func (m *ProviderManifest) CapabilityEntrypoint(name string) *manifest.Entrypoint {
+	if c, ok := m.Capabilities[name]; ok && c.Entrypoint != nil && c.Entrypoint.Executable != "" {
+		return c.Entrypoint
 	}
 	return nil
 }
@@ -177,3 +177,57 @@ func GetProviderMetadata(providerDir string) (name, version string, err error) {
 
 	return manifest.Metadata.Name, manifest.Metadata.Version, nil
 }
+
+// Provider runtimes
+const (
+	RuntimeNative = "native"
+	RuntimeWASI   = "wasi"
+)
+
+// SupportsRuntime reports whether name is the default runtime or listed in
+// runtime.supported, either as a plain string or as a map with a name/type key
+func (m *ProviderManifest) SupportsRuntime(name string) bool {
+	if m.Runtime.Default == name {
+		return true
+	}
+	for _, entry := range m.Runtime.Supported {
+		switch v := entry.(type) {
+		case string:
+			if v == name {
+				return true
+			}
+		case map[string]interface{}:
+			for _, key := range []string{"name", "type", "runtime"} {
+				if v[key] == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// WASIModulePath returns the path of the provider's wasm module
+func (m *ProviderManifest) WASIModulePath(providerDir string) string {
+	if m.Runtime.WASI.Module != "" {
+		return filepath.Join(providerDir, m.Runtime.WASI.Module)
+	}
+	return filepath.Join(providerDir, "bin", "provider.wasm")
+}
+
+// SelectRuntime picks the runtime for a provider installed at providerDir:
+// wasi when it is the default, or when it is supported and no native binary
+// is installed for this platform
+func (m *ProviderManifest) SelectRuntime(providerDir string) string {
+	if m.Runtime.Default == RuntimeWASI {
+		return RuntimeWASI
+	}
+	if m.SupportsRuntime(RuntimeWASI) {
+		if _, err := ResolveEntrypoint(providerDir, m); err != nil {
+			if _, err := os.Stat(m.WASIModulePath(providerDir)); err == nil {
+				return RuntimeWASI
+			}
+		}
+	}
+	return RuntimeNative
+}
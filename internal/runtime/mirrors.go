@@ -0,0 +1,111 @@
+package runtime
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// RegistryConfig holds connection settings for one registry host
+type RegistryConfig struct {
+	// Mirrors are tried in order before the registry itself. Each is a
+	// registry host with an optional path prefix, e.g. "mirror.corp/dockerhub".
+	Mirrors []string `yaml:"mirrors"`
+
+	// Insecure skips TLS certificate verification
+	Insecure bool `yaml:"insecure"`
+
+	// PlainHTTP talks to the registry over http instead of https
+	PlainHTTP bool `yaml:"plainHTTP"`
+
+	// CA is a PEM bundle of root certificates trusted for the registry in
+	// addition to the system roots, e.g. the root of an inspection proxy
+	CA string `yaml:"ca"`
+
+	// Cert and Key are a PEM client certificate and private key presented
+	// to registries that require mutual TLS
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+// resolvePaths makes relative certificate paths relative to dir
+func (r *RegistryConfig) resolvePaths(dir string) {
+	for _, p := range []*string{&r.CA, &r.Cert, &r.Key} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+}
+
+// Rewrite redirects references under From to To,
+// e.g. ghcr.io/sourceplane to registry.corp/sourceplane
+type Rewrite struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// registries and rewrites are set from config by ConfigureRegistries
+var (
+	registries = map[string]RegistryConfig{}
+	rewrites   []Rewrite
+)
+
+// ConfigureRegistries applies the registry settings, rewrite rules and
+// retry policy of cfg to subsequent registry access
+func ConfigureRegistries(cfg *Config) {
+	registries = cfg.Registries
+	if registries == nil {
+		registries = map[string]RegistryConfig{}
+	}
+	rewrites = cfg.Rewrites
+	retry = cfg.Retry.withDefaults()
+}
+
+// registryConfig returns the settings for a registry host
+func registryConfig(host string) RegistryConfig {
+	return registries[host]
+}
+
+// rewriteRepository applies the first matching rewrite rule to repository
+// (registry/path, without tag or digest). Rules match whole path segments.
+func rewriteRepository(repository string) string {
+	for _, r := range rewrites {
+		from := strings.TrimSuffix(r.From, "/")
+		if from == "" {
+			continue
+		}
+		if repository == from || strings.HasPrefix(repository, from+"/") {
+			return strings.TrimSuffix(r.To, "/") + repository[len(from):]
+		}
+	}
+	return repository
+}
+
+// registryHost returns the registry part of repository
+func registryHost(repository string) string {
+	if i := strings.Index(repository, "/"); i >= 0 {
+		return repository[:i]
+	}
+	return repository
+}
+
+// pullSource is one location a repository can be pulled from
+type pullSource struct {
+	Repository string // registry/path
+	Mirror     string // the mirror serving Repository, "" for the registry itself
+}
+
+// pullSources returns the locations repository can be pulled from after
+// rewrites: the mirrors configured for its registry in order, then the
+// registry itself
+func pullSources(repository string) []pullSource {
+	repository = rewriteRepository(repository)
+	host := registryHost(repository)
+	path := strings.TrimPrefix(repository, host+"/")
+
+	var sources []pullSource
+	for _, mirror := range registryConfig(host).Mirrors {
+		mirror = strings.TrimSuffix(mirror, "/")
+		sources = append(sources, pullSource{Repository: mirror + "/" + path, Mirror: mirror})
+	}
+	return append(sources, pullSource{Repository: repository})
+}
@@ -5,11 +5,10 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -18,65 +17,65 @@ import (
 	"time"
 
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	oras "oras.land/oras-go/v2"
 	"oras.land/oras-go/v2/content"
 	"oras.land/oras-go/v2/content/memory"
 	"oras.land/oras-go/v2/registry/remote"
-	"oras.land/oras-go/v2/registry/remote/auth"
 )
 
+// wasmMediaType is the layer media type of a platform-independent wasi module
+const wasmMediaType = "application/vnd.sourceplane.wasm.v1"
+
 // PullProviderOCI pulls a provider from an OCI registry and extracts platform-specific files.
-// Uses oras.Copy for efficient, concurrent layer downloads.
-func PullProviderOCI(ctx context.Context, imageRef string, providerName string) error {
-	providerBaseDir := filepath.Join(ThinHome(), "providers", providerName)
-	if err := os.MkdirAll(providerBaseDir, 0755); err != nil {
-		return fmt.Errorf("failed to create provider directory: %w", err)
+// Uses oras.CopyGraph for efficient, concurrent layer downloads.
+func PullProviderOCI(ctx context.Context, imageRef string, providerName string) (err error) {
+	ctx, span := StartSpan(ctx, "thin.pull",
+		attribute.String("thin.provider.name", providerName),
+		attribute.String("thin.image.ref", imageRef),
+	)
+	defer func() { EndSpan(span, err) }()
+
+	providerBaseDir, err := installedProviderDir(providerName)
+	if err != nil {
+		return err
 	}
 
-	handler := NewStatusHandler()
-	defer handler.Close()
-
-	fmt.Printf("Downloading %s from %s...\n", providerName, imageRef)
-
-	// Normalize image reference
-	ref := imageRef
-	if !strings.Contains(ref, "/") {
-		ref = "docker.io/" + ref
+	// Concurrent installs of the same provider would extract over each other
+	started := time.Now()
+	lock, err := LockProvider(ctx, providerName)
+	if err != nil {
+		return err
 	}
-	if !strings.Contains(ref, ":") && !strings.Contains(ref, "@") {
-		ref = ref + ":latest"
+	defer lock.Unlock()
+	if installedConcurrently(providerBaseDir, imageRef, started) {
+		fmt.Fprintf(messageOutput, "✓ Provider %s was installed from %s by another thin process, reusing it\n", providerName, imageRef)
+		return nil
 	}
 
-	// Connect to registry
-	repo, err := remote.NewRepository(ref)
+	// Extract into a staging directory next to providerBaseDir, which only
+	// replaces the installed provider once it is complete
+	if err := os.MkdirAll(filepath.Dir(providerBaseDir), 0755); err != nil {
+		return fmt.Errorf("failed to create provider directory: %w", err)
+	}
+	stagingDir, err := os.MkdirTemp(filepath.Dir(providerBaseDir), "."+providerName+".staging-*")
 	if err != nil {
-		return fmt.Errorf("failed to parse image reference %s: %w", ref, err)
-	}
-
-	// Optimized HTTP transport — no Client.Timeout (it kills in-flight body reads)
-	repo.Client = &auth.Client{
-		Client: &http.Client{
-			Transport: &http.Transport{
-				ForceAttemptHTTP2:     true,
-				MaxIdleConns:          10,
-				MaxIdleConnsPerHost:   4,
-				MaxConnsPerHost:       8,
-				IdleConnTimeout:       30 * time.Second,
-				TLSHandshakeTimeout:   10 * time.Second,
-				ResponseHeaderTimeout: 30 * time.Second,
-				ExpectContinueTimeout: 5 * time.Second,
-				WriteBufferSize:       256 * 1024,
-				ReadBufferSize:        256 * 1024,
-				TLSClientConfig:       &tls.Config{MinVersion: tls.VersionTLS12},
-			},
-		},
-		Cache: auth.NewCache(),
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer removeProviderTree(stagingDir) // a no-op once it has been moved into place
+	if err := os.Chmod(stagingDir, 0755); err != nil {
+		return err
 	}
 
-	// Extract tag
-	tag := "latest"
-	if idx := strings.LastIndex(ref, ":"); idx >= 0 {
-		tag = ref[idx+1:]
+	handler := NewStatusHandler()
+	defer handler.Close()
+
+	fmt.Fprintf(messageOutput, "Downloading %s from %s...\n", providerName, imageRef)
+
+	ref, err := ParseImageReference(imageRef)
+	if err != nil {
+		return err
 	}
 
 	// Build the set of media types we want for this platform
@@ -87,91 +86,121 @@ func PullProviderOCI(ctx context.Context, imageRef string, providerName string)
 	wantedTypes := map[string]bool{
 		"application/vnd.sourceplane.provider.v1": true,
 		"application/vnd.sourceplane.assets.v1":   true,
+		wasmMediaType:                             true,
 		binaryMediaType:                           true,
 	}
 
 	// Track which layers we actually download for progress display
 	var mu sync.Mutex
 	startTimes := map[string]time.Time{}
+	layerSpans := map[string]trace.Span{}
 
 	// In-memory target store for the copy
 	memStore := memory.New()
 
-	// Use oras.Copy — handles manifest resolution, concurrent layer downloads,
-	// deduplication, and streaming in one call
-	copyOpts := oras.CopyOptions{
-		CopyGraphOptions: oras.CopyGraphOptions{
-			Concurrency: 4, // parallel layer downloads
-
-			// Filter to only download platform-relevant layers
-			FindSuccessors: func(ctx context.Context, fetcher content.Fetcher, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
-				successors, err := content.Successors(ctx, fetcher, desc)
-				if err != nil {
-					return nil, err
-				}
+	// Use oras.CopyGraph — handles concurrent layer downloads, deduplication,
+	// and streaming in one call; the root is resolved separately so it can be traced
+	copyOpts := oras.CopyGraphOptions{
+		Concurrency: 4, // parallel layer downloads
 
-				// For the manifest node, filter layers to platform-relevant ones
-				if desc.MediaType == ocispec.MediaTypeImageManifest ||
-					desc.MediaType == "application/vnd.oci.image.manifest.v1+json" {
-					var filtered []ocispec.Descriptor
-					foundBinary := false
-					for _, s := range successors {
-						if wantedTypes[s.MediaType] {
-							filtered = append(filtered, s)
-							if s.MediaType == binaryMediaType {
-								foundBinary = true
-							}
-						} else if s.MediaType == ocispec.MediaTypeImageConfig ||
-							s.MediaType == "application/vnd.oci.image.config.v1+json" {
-							// Always include the config
-							filtered = append(filtered, s)
+		// Filter to only download platform-relevant layers
+		FindSuccessors: func(ctx context.Context, fetcher content.Fetcher, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+			successors, err := content.Successors(ctx, fetcher, desc)
+			if err != nil {
+				return nil, err
+			}
+
+			// For the manifest node, filter layers to platform-relevant ones
+			if desc.MediaType == ocispec.MediaTypeImageManifest ||
+				desc.MediaType == "application/vnd.oci.image.manifest.v1+json" {
+				var filtered []ocispec.Descriptor
+				foundBinary := false
+				for _, s := range successors {
+					if wantedTypes[s.MediaType] {
+						filtered = append(filtered, s)
+						if s.MediaType == binaryMediaType || s.MediaType == wasmMediaType {
+							foundBinary = true
 						}
-						// Skip other platform binaries and empty layers
+					} else if s.MediaType == ocispec.MediaTypeImageConfig ||
+						s.MediaType == "application/vnd.oci.image.config.v1+json" {
+						// Always include the config
+						filtered = append(filtered, s)
 					}
-					if !foundBinary {
-						// Fallback: include all non-empty layers for backwards compat
-						fmt.Printf("⚠ No binary for %s/%s, downloading all layers...\n", currentOS, currentArch)
-						filtered = nil
-						for _, s := range successors {
-							if s.MediaType != "application/vnd.oci.empty.v1+json" {
-								filtered = append(filtered, s)
-							}
+					// Skip other platform binaries and empty layers
+				}
+				if !foundBinary {
+					// Fallback: include all non-empty layers for backwards compat
+					handler.Message("⚠ No binary for %s/%s, downloading all layers...", currentOS, currentArch)
+					filtered = nil
+					for _, s := range successors {
+						if s.MediaType != "application/vnd.oci.empty.v1+json" {
+							filtered = append(filtered, s)
 						}
 					}
-					if len(filtered) > 0 {
-						fmt.Printf("✓ Fetching %d layers (platform: %s/%s)...\n", len(filtered), currentOS, currentArch)
-					}
-					return filtered, nil
 				}
-				return successors, nil
-			},
-
-			PreCopy: func(ctx context.Context, desc ocispec.Descriptor) error {
-				mu.Lock()
-				startTimes[desc.Digest.String()] = time.Now()
-				mu.Unlock()
-				handler.OnNodeDownloading(desc)
-				return nil
-			},
-
-			PostCopy: func(ctx context.Context, desc ocispec.Descriptor) error {
-				handler.OnNodeDownloaded(desc)
-				return nil
-			},
-
-			OnCopySkipped: func(ctx context.Context, desc ocispec.Descriptor) error {
-				handler.OnNodeSkipped(desc)
-				return nil
-			},
+				if len(filtered) > 0 {
+					handler.Message("✓ Fetching %d layers (platform: %s/%s)...", len(filtered), currentOS, currentArch)
+				}
+				return filtered, nil
+			}
+			return successors, nil
+		},
+
+		PreCopy: func(ctx context.Context, desc ocispec.Descriptor) error {
+			_, layerSpan := StartSpan(ctx, "thin.pull.layer",
+				attribute.String("oci.digest", desc.Digest.String()),
+				attribute.String("oci.media_type", desc.MediaType),
+				attribute.Int64("oci.size", desc.Size),
+			)
+			mu.Lock()
+			startTimes[desc.Digest.String()] = time.Now()
+			layerSpans[desc.Digest.String()] = layerSpan
+			mu.Unlock()
+			handler.OnNodeDownloading(desc)
+			return nil
 		},
+
+		PostCopy: func(ctx context.Context, desc ocispec.Descriptor) error {
+			mu.Lock()
+			if layerSpan, ok := layerSpans[desc.Digest.String()]; ok {
+				layerSpan.End()
+				delete(layerSpans, desc.Digest.String())
+			}
+			mu.Unlock()
+			handler.OnNodeDownloaded(desc)
+			return nil
+		},
+
+		OnCopySkipped: func(ctx context.Context, desc ocispec.Descriptor) error {
+			handler.OnNodeSkipped(desc)
+			return nil
+		},
+	}
+
+	repo, rootDesc, err := resolveSource(ctx, ref, handler)
+	if err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
 	}
 
-	fmt.Printf("Pulling from %s...\n", ref)
-	rootDesc, err := oras.Copy(ctx, repo, tag, memStore, tag, copyOpts)
+	copyCtx, copySpan := StartSpan(ctx, "thin.pull.copy", attribute.String("oci.digest", rootDesc.Digest.String()))
+	// Reads go through progressStorage so the handler sees bytes as they
+	// arrive, and resumableStorage so dropped blob downloads pick up where
+	// they stopped
+	src := &progressStorage{ReadOnlyStorage: &resumableStorage{Repository: repo}, handler: handler}
+	err = oras.CopyGraph(copyCtx, src, memStore, rootDesc, copyOpts)
+	mu.Lock()
+	for _, layerSpan := range layerSpans {
+		// Layers still open here failed mid-copy
+		layerSpan.End()
+	}
+	mu.Unlock()
+	EndSpan(copySpan, err)
+	// Print the pull summary before extraction output
+	handler.Close()
 	if err != nil {
 		return fmt.Errorf("failed to pull image: %w", err)
 	}
-	fmt.Printf("✓ Pulled manifest %s\n", rootDesc.Digest.String()[:16])
+	fmt.Fprintf(messageOutput, "✓ Pulled manifest %s\n", rootDesc.Digest.String()[:16])
 
 	// Now extract the downloaded content from the memory store
 	// Fetch the manifest to find layers
@@ -190,6 +219,9 @@ func PullProviderOCI(ctx context.Context, imageRef string, providerName string)
 		return fmt.Errorf("failed to parse manifest: %w", err)
 	}
 
+	_, extractSpan := StartSpan(ctx, "thin.pull.extract")
+	defer extractSpan.End()
+
 	// Extract each layer from the memory store
 	for _, layer := range manifest.Layers {
 		if layer.MediaType == "application/vnd.oci.empty.v1+json" {
@@ -209,7 +241,7 @@ func PullProviderOCI(ctx context.Context, imageRef string, providerName string)
 		if err != nil {
 			return fmt.Errorf("failed to read layer %s: %w", layer.Digest.String()[:16], err)
 		}
-		if err := extractLayerContent(layerData, providerBaseDir); err != nil {
+		if err := extractLayerContent(layerData, stagingDir); err != nil {
 			return fmt.Errorf("failed to extract layer: %w", err)
 		}
 	}
@@ -219,22 +251,22 @@ func PullProviderOCI(ctx context.Context, imageRef string, providerName string)
 		if exists, _ := memStore.Exists(ctx, manifest.Config); exists {
 			configData, err := content.FetchAll(ctx, memStore, manifest.Config)
 			if err == nil {
-				extractLayerContent(configData, providerBaseDir)
+				extractLayerContent(configData, stagingDir)
 			}
 		}
 	}
 
 	// Ensure directory structure
 	for _, dir := range []string{"bin", "assets"} {
-		os.MkdirAll(filepath.Join(providerBaseDir, dir), 0755)
+		os.MkdirAll(filepath.Join(stagingDir, dir), 0755)
 	}
 
 	// Relocate oci/ subdirectory if extraction created one
-	ociDir := filepath.Join(providerBaseDir, "oci")
+	ociDir := filepath.Join(stagingDir, "oci")
 	if stat, err := os.Stat(ociDir); err == nil && stat.IsDir() {
 		for _, item := range []string{"bin", "assets"} {
 			src := filepath.Join(ociDir, item)
-			dst := filepath.Join(providerBaseDir, item)
+			dst := filepath.Join(stagingDir, item)
 			if srcStat, err := os.Stat(src); err == nil && srcStat.IsDir() {
 				if err := copyDir(src, dst); err == nil {
 					os.RemoveAll(src)
@@ -245,26 +277,133 @@ func PullProviderOCI(ctx context.Context, imageRef string, providerName string)
 	}
 
 	// Verify provider manifest
-	manifestPath := filepath.Join(providerBaseDir, "thin.provider.yaml")
-	if _, err := os.Stat(manifestPath); err != nil {
-		fmt.Printf("⚠ Warning: provider manifest not found at %s\n", manifestPath)
+	if _, err := os.Stat(filepath.Join(stagingDir, "thin.provider.yaml")); err != nil {
+		fmt.Fprintf(messageOutput, "⚠ Warning: provider manifest not found at %s\n", filepath.Join(providerBaseDir, "thin.provider.yaml"))
+	}
+
+	// Record checksums of immutable assets and make them read-only
+	if providerManifest, err := ReadProviderManifest(stagingDir); err == nil && providerManifest != nil && providerManifest.AssetsImmutable() {
+		if err := SealAssets(stagingDir, providerManifest); err != nil {
+			return err
+		}
+		fmt.Fprintf(messageOutput, "✓ Assets sealed: %s\n", filepath.Base(providerManifest.AssetsDir(stagingDir)))
 	}
 
 	// Verify and chmod binary
-	binPath, err := GetPlatformBinaryPath(providerBaseDir)
-	if err != nil {
-		fmt.Printf("⚠ Warning: %v\n", err)
-	} else {
+	wasmPath := filepath.Join(stagingDir, "bin", "provider.wasm")
+	binPath, err := GetPlatformBinaryPath(stagingDir)
+	if _, wasmErr := os.Stat(wasmPath); wasmErr == nil {
+		// A wasi module runs on every platform
+		fmt.Fprintf(messageOutput, "✓ WASI module ready: %s\n", filepath.Base(wasmPath))
+	} else if err != nil {
+		fmt.Fprintf(messageOutput, "⚠ Warning: %v\n", err)
+	}
+	if err == nil {
 		if err := os.Chmod(binPath, 0755); err != nil {
 			return fmt.Errorf("failed to make binary executable: %w", err)
 		}
-		fmt.Printf("✓ Binary ready: %s\n", filepath.Base(binPath))
+		fmt.Fprintf(messageOutput, "✓ Binary ready: %s\n", filepath.Base(binPath))
+	}
+
+	// Record what was installed so `thin provider verify` can detect changes
+	if err := WriteReceipt(stagingDir, providerName, imageRef, rootDesc.Digest.String()); err != nil {
+		return fmt.Errorf("failed to write install receipt: %w", err)
+	}
+
+	if err := replaceProviderDir(providerBaseDir, stagingDir); err != nil {
+		return fmt.Errorf("failed to install provider %s: %w", providerName, err)
+	}
+
+	fmt.Fprintf(messageOutput, "✓ Provider %s installed from %s\n", providerName, imageRef)
+	return nil
+}
+
+// resolveSource resolves ref in the first of its pull sources that has it
+// and returns the connected repository with the root descriptor. Mirrors
+// that fail are reported and skipped.
+func resolveSource(ctx context.Context, ref *ImageReference, handler StatusHandler) (*remote.Repository, ocispec.Descriptor, error) {
+	sources := pullSources(ref.Name())
+	var errs []error
+	for i, source := range sources {
+		sourceRef := ref.WithName(source.Repository).String()
+		handler.Message("Pulling from %s...", sourceRef)
+
+		resolveCtx, resolveSpan := StartSpan(ctx, "thin.pull.resolve",
+			attribute.String("oci.reference", sourceRef),
+			attribute.String("thin.registry.mirror", source.Mirror),
+		)
+		repo, err := newRepository(source.Repository)
+		var desc ocispec.Descriptor
+		if err == nil {
+			desc, err = repo.Resolve(resolveCtx, ref.Reference())
+		}
+		if err == nil && ref.Digest != "" && desc.Digest.String() != ref.Digest {
+			err = fmt.Errorf("registry returned manifest %s for pinned digest %s", desc.Digest, ref.Digest)
+		}
+		EndSpan(resolveSpan, err)
+		if err == nil {
+			return repo, desc, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", sourceRef, err))
+		if i < len(sources)-1 {
+			handler.Message("⚠ Could not resolve %s (%v), trying %s", sourceRef, err, sources[i+1].Repository)
+		}
+	}
+	if len(errs) == 1 {
+		return nil, ocispec.Descriptor{}, errors.Unwrap(errs[0])
+	}
+	return nil, ocispec.Descriptor{}, errors.Join(errs...)
+}
+
+// replaceProviderDir moves the complete install in stagingDir to dir. A
+// provider already installed at dir is moved aside first and only deleted
+// once the new one is in place, or put back if that fails.
+func replaceProviderDir(dir, stagingDir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return os.Rename(stagingDir, dir)
 	}
 
-	fmt.Printf("✓ Provider %s installed from %s\n", providerName, imageRef)
+	old := stagingDir + ".old"
+	if err := os.Rename(dir, old); err != nil {
+		return err
+	}
+	if err := os.Rename(stagingDir, dir); err != nil {
+		if restoreErr := os.Rename(old, dir); restoreErr != nil {
+			return fmt.Errorf("%w (the previous install was left at %s: %v)", err, old, restoreErr)
+		}
+		return err
+	}
+	if err := removeProviderTree(old); err != nil {
+		fmt.Fprintf(messageOutput, "⚠ Warning: failed to remove previous install at %s: %v\n", old, err)
+	}
 	return nil
 }
 
+// removeProviderTree deletes a provider directory, unsealing its assets
+// first: they are read-only and can't be deleted on every platform
+func removeProviderTree(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	if err := UnsealAssets(dir); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// installedConcurrently reports whether another process installed imageRef
+// into providerDir after since, e.g. while we waited for the provider lock,
+// and the result is intact
+func installedConcurrently(providerDir, imageRef string, since time.Time) bool {
+	receipt, err := ReadReceipt(providerDir)
+	if err != nil || receipt.Source != imageRef || receipt.InstalledAt.Before(since) {
+		return false
+	}
+	result, err := VerifyProvider(providerDir)
+	return err == nil && result.OK()
+}
+
 // extractLayerContent extracts tar/tar.gz layer content to target directory
 func extractLayerContent(layerData []byte, targetDir string) error {
 	// Check if it's a gzipped tar
@@ -277,6 +416,15 @@ func extractLayerContent(layerData []byte, targetDir string) error {
 		return extractTar(bytes.NewReader(layerData), targetDir)
 	}
 
+	// Check if it's a raw WebAssembly module
+	if bytes.HasPrefix(layerData, []byte("\x00asm")) {
+		wasmPath := filepath.Join(targetDir, "bin", "provider.wasm")
+		if err := os.MkdirAll(filepath.Dir(wasmPath), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(wasmPath, layerData, 0644)
+	}
+
 	// Check if it's a raw binary (Mach-O, ELF, etc.) - 4.4MB+
 	if len(layerData) > 4000000 {
 		// Large binary file - extract directly to bin/entrypoint
@@ -381,38 +529,30 @@ func isTar(data []byte) bool {
 
 // GetPlatformBinaryPath returns the path to the platform-specific binary for a provider
 func GetPlatformBinaryPath(providerDir string) (string, error) {
-	goos := runtime.GOOS
-	arch := runtime.GOARCH
-
-	// Try new platform-specific flat structure: bin/entrypoint (from platform-specific layer)
-	flatBinaryPath := filepath.Join(providerDir, "bin", "entrypoint")
-	if _, err := os.Stat(flatBinaryPath); err == nil {
-		return flatBinaryPath, nil
-	}
-
-	// Try alternate names in flat structure
-	for _, name := range []string{"thin", "provider"} {
-		altPath := filepath.Join(providerDir, "bin", name)
-		if _, err := os.Stat(altPath); err == nil {
-			return altPath, nil
+	for _, path := range platformBinaryCandidates(providerDir) {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
 		}
 	}
 
-	// Try old multi-platform nested structure: bin/{os}/{arch}/entrypoint
-	nestedBinaryPath := filepath.Join(providerDir, "bin", goos, arch, "entrypoint")
-	if _, err := os.Stat(nestedBinaryPath); err == nil {
-		return nestedBinaryPath, nil
-	}
+	goos, arch := runtime.GOOS, runtime.GOARCH
+	return "", fmt.Errorf("binary not found for platform %s/%s (checked bin/entrypoint and bin/%s/%s/entrypoint)", goos, arch, goos, arch)
+}
 
-	// Try alternate names in nested structure
-	for _, name := range []string{"thin", "provider"} {
-		altPath := filepath.Join(providerDir, "bin", goos, arch, name)
-		if _, err := os.Stat(altPath); err == nil {
-			return altPath, nil
+// platformBinaryCandidates lists the binary locations of the flat
+// platform-specific layout (bin/<name>) and the older multi-platform
+// layout (bin/<os>/<arch>/<name>), in the order they are tried
+func platformBinaryCandidates(providerDir string) []string {
+	var candidates []string
+	for _, dir := range []string{
+		filepath.Join(providerDir, "bin"),
+		filepath.Join(providerDir, "bin", runtime.GOOS, runtime.GOARCH),
+	} {
+		for _, name := range []string{"entrypoint", "thin", "provider"} {
+			candidates = append(candidates, filepath.Join(dir, name))
 		}
 	}
-
-	return "", fmt.Errorf("binary not found for platform %s/%s (checked bin/entrypoint and bin/%s/%s/entrypoint)", goos, arch, goos, arch)
+	return candidates
 }
 
 // copyDir recursively copies a directory
@@ -0,0 +1,93 @@
+//go:build !windows
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// startProcess starts cmd. With group set it runs in a process group of its
+// own, so that everything the provider started can be killed. When thin
+// runs in the foreground of a terminal that group is made the terminal's
+// foreground group, as a shell would, so the provider can still read from
+// the terminal and gets Ctrl-C directly. release hands the terminal back
+// and must be called once cmd has been waited for.
+//
+// When ctx is cancelled by a signal (see NotifySignals) the signal is passed
+// on to the provider, which then gets waitDelay to exit; any other
+// cancellation kills it, with its group, outright.
+func startProcess(ctx context.Context, cmd *exec.Cmd, group bool) (release func(), err error) {
+	tty := -1
+	if group {
+		attr := &syscall.SysProcAttr{Setpgid: true}
+		if tty = foregroundTerminal(); tty >= 0 {
+			attr.Foreground = true
+			attr.Ctty = tty
+		}
+		cmd.SysProcAttr = attr
+	}
+	// Sharing thin's foreground group, the provider already gets the
+	// SIGINT and SIGHUP the terminal sends
+	sharesTerminal := !group && foregroundTerminal() >= 0
+
+	cmd.Cancel = func() error {
+		pid := cmd.Process.Pid
+		if group {
+			pid = -pid
+		}
+		sig := syscall.SIGKILL
+		if s, ok := signalCause(ctx); ok {
+			if s, ok := s.(syscall.Signal); ok {
+				sig = s
+			}
+			if sharesTerminal && sig != syscall.SIGTERM {
+				return nil
+			}
+		}
+		err := syscall.Kill(pid, sig)
+		if errors.Is(err, syscall.ESRCH) {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return func() {
+		if tty >= 0 {
+			reclaimTerminal(tty)
+		}
+	}, nil
+}
+
+// foregroundTerminal returns the file descriptor of stdin if it is a
+// terminal whose foreground process group is thin's, and -1 otherwise
+func foregroundTerminal() int {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return -1
+	}
+	pgrp, err := unix.IoctlGetInt(fd, unix.TIOCGPGRP)
+	if err != nil || pgrp != syscall.Getpgrp() {
+		return -1
+	}
+	return fd
+}
+
+// reclaimTerminal makes thin's process group the foreground group of tty
+// again. thin is in the background until it does, so SIGTTOU, which would
+// otherwise stop it, is ignored meanwhile.
+func reclaimTerminal(tty int) {
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	unix.IoctlSetPointerInt(tty, unix.TIOCSPGRP, syscall.Getpgrp())
+}
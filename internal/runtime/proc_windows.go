@@ -0,0 +1,58 @@
+//go:build windows
+
+package runtime
+
+import (
+	"context"
+	"os/exec"
+	"sync/atomic"
+
+	"golang.org/x/sys/windows"
+)
+
+// startProcess starts cmd. With group set it runs in a job object, so that
+// everything the provider started can be terminated; processes it starts
+// before it is assigned to the job are not covered. release closes the job
+// and must be called once cmd has been waited for.
+//
+// When ctx is cancelled by a signal (see NotifySignals) the provider, which
+// shares thin's console, has had the same Ctrl-C and gets waitDelay to exit;
+// any other cancellation terminates it outright.
+func startProcess(ctx context.Context, cmd *exec.Cmd, group bool) (release func(), err error) {
+	if !group {
+		cmd.Cancel = func() error {
+			if _, ok := signalCause(ctx); ok {
+				return nil
+			}
+			return cmd.Process.Kill()
+		}
+		return func() {}, cmd.Start()
+	}
+
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var inJob atomic.Bool
+	cmd.Cancel = func() error {
+		if _, ok := signalCause(ctx); ok {
+			return nil
+		}
+		if !inJob.Load() {
+			return cmd.Process.Kill()
+		}
+		return windows.TerminateJobObject(job, 1)
+	}
+
+	if err := cmd.Start(); err != nil {
+		windows.CloseHandle(job)
+		return nil, err
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(cmd.Process.Pid))
+	if err == nil {
+		// Without the job, cancelling falls back to killing the provider itself
+		inJob.Store(windows.AssignProcessToJobObject(job, process) == nil)
+		windows.CloseHandle(process)
+	}
+	return func() { windows.CloseHandle(job) }, nil
+}
@@ -0,0 +1,41 @@
+package runtime
+
+import (
+	"context"
+	"io"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+)
+
+// progressStorage wraps a copy source so that bytes read from every fetched
+// blob are reported to a StatusHandler
+type progressStorage struct {
+	content.ReadOnlyStorage
+	handler StatusHandler
+}
+
+func (s *progressStorage) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	rc, err := s.ReadOnlyStorage.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	return &progressReader{ReadCloser: rc, digest: desc.Digest.String(), handler: s.handler}, nil
+}
+
+// progressReader counts the bytes read from a blob stream
+type progressReader struct {
+	io.ReadCloser
+	digest  string
+	read    int64
+	handler StatusHandler
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.read += int64(n)
+		r.handler.UpdateProgress(r.digest, r.read)
+	}
+	return n, err
+}
@@ -1,60 +1,291 @@
 package runtime
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 type ProviderRef struct {
+	// Registry is set for registry-qualified references such as
+	// ghcr.io/acme/ci@v1 and says where the provider can be installed from
+	Registry  string `yaml:"registry,omitempty"`
 	Namespace string `yaml:"namespace"`
 	Name      string `yaml:"name"`
 	Version   string `yaml:"version"`
 }
 
+// ErrInvalidProviderRef is returned for references that aren't
+// [registry/]namespace/name@version
+var ErrInvalidProviderRef = errors.New("invalid provider reference")
+
+// ParseProviderRef parses namespace/name@version, or
+// registry/namespace/name@version where the registry contains a '.' or ':'
+// or is localhost and the namespace may span several path components
 func ParseProviderRef(ref string) (*ProviderRef, error) {
 	parts := strings.Split(ref, "@")
-	if len(parts) != 2 {
-		return nil, errors.New("invalid provider reference")
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, ErrInvalidProviderRef
 	}
 
-	nsName := strings.Split(parts[0], "/")
-	if len(nsName) != 2 {
-		return nil, errors.New("invalid provider reference")
+	path := strings.Split(parts[0], "/")
+	registry := ""
+	if len(path) > 2 && isRegistryHost(path[0]) {
+		registry, path = path[0], path[1:]
+	}
+	if len(path) < 2 || (registry == "" && len(path) != 2) {
+		return nil, ErrInvalidProviderRef
+	}
+	// Components become directories of the provider store
+	for _, component := range append(path, parts[1]) {
+		if component == "" || component == "." || component == ".." || strings.ContainsAny(component, `/\`) {
+			return nil, ErrInvalidProviderRef
+		}
 	}
 
 	return &ProviderRef{
-		Namespace: nsName[0],
-		Name:      nsName[1],
+		Registry:  registry,
+		Namespace: strings.Join(path[:len(path)-1], "/"),
+		Name:      path[len(path)-1],
 		Version:   parts[1],
 	}, nil
 }
 
-func activeProviderPath() string {
-	return filepath.Join(ThinHome(), "active-provider.yaml")
+// String formats the reference as [registry/]namespace/name@version
+func (r *ProviderRef) String() string {
+	if r.Registry != "" {
+		return fmt.Sprintf("%s/%s/%s@%s", r.Registry, r.Namespace, r.Name, r.Version)
+	}
+	return fmt.Sprintf("%s/%s@%s", r.Namespace, r.Name, r.Version)
+}
+
+// Matches reports whether r and other refer to the same provider version.
+// The registry is where a provider comes from, not part of its identity.
+func (r *ProviderRef) Matches(other *ProviderRef) bool {
+	return other != nil && r.Namespace == other.Namespace && r.Name == other.Name && r.Version == other.Version
+}
+
+// ActiveProvider is a provider enabled in the project context.
+// Higher priority wins when several active providers define the same command.
+type ActiveProvider struct {
+	ProviderRef `yaml:",inline"`
+	Priority    int `yaml:"priority,omitempty"`
 }
 
+// activeProvidersFile is the on-disk format of active-provider.yaml.
+// Older files hold a single ProviderRef at the top level.
+type activeProvidersFile struct {
+	Providers []*ActiveProvider `yaml:"providers"`
+}
+
+// ActiveProviderPath returns the project context file listing the active
+// providers
+func ActiveProviderPath() string {
+	return filepath.Join(ContextDir(), "active-provider.yaml")
+}
+
+// WriteActiveProvider makes ref the only active provider
 func WriteActiveProvider(ref *ProviderRef) error {
-	b, err := yaml.Marshal(ref)
+	return WriteActiveProviders([]*ActiveProvider{{ProviderRef: *ref}})
+}
+
+// WriteActiveProviders replaces the set of active providers
+func WriteActiveProviders(providers []*ActiveProvider) error {
+	lock, err := lockActiveProviders()
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(activeProviderPath(), b, 0644)
+	defer lock.Unlock()
+	return writeActiveProviders(providers)
 }
 
-func ReadActiveProvider() (*ProviderRef, error) {
-	b, err := os.ReadFile(activeProviderPath())
+// writeActiveProviders replaces active-provider.yaml atomically.
+// The caller must hold the active providers lock.
+func writeActiveProviders(providers []*ActiveProvider) error {
+	b, err := yaml.Marshal(&activeProvidersFile{Providers: providers})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(ActiveProviderPath(), b, 0644)
+}
+
+// AddActiveProvider activates ref alongside the existing active providers,
+// updating its priority if it is already active
+func AddActiveProvider(ref *ProviderRef, priority int) error {
+	lock, err := lockActiveProviders()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	providers, err := ReadActiveProviders()
+	if err != nil && !errors.Is(err, ErrNoActiveProvider) {
+		return err
+	}
+
+	for _, p := range providers {
+		if p.ProviderRef.Matches(ref) {
+			p.Priority = priority
+			return writeActiveProviders(providers)
+		}
+	}
+	return writeActiveProviders(append(providers, &ActiveProvider{ProviderRef: *ref, Priority: priority}))
+}
+
+// RemoveActiveProvider deactivates ref
+func RemoveActiveProvider(ref *ProviderRef) error {
+	lock, err := lockActiveProviders()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	providers, err := ReadActiveProviders()
+	if err != nil {
+		return err
+	}
+
+	var kept []*ActiveProvider
+	for _, p := range providers {
+		if !p.ProviderRef.Matches(ref) {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == len(providers) {
+		return fmt.Errorf("provider %s is not active", ref)
+	}
+	return writeActiveProviders(kept)
+}
+
+// ErrNoActiveProvider is returned when the project context has no active provider
+var ErrNoActiveProvider = errors.New("no active provider set")
+
+// ReadActiveProviders returns the active providers, highest priority first.
+// Providers with equal priority keep the order in which they were added.
+func ReadActiveProviders() ([]*ActiveProvider, error) {
+	b, err := os.ReadFile(ActiveProviderPath())
 	if err != nil {
-		return nil, errors.New("no active provider set")
+		return nil, ErrNoActiveProvider
 	}
-	var ref ProviderRef
-	if err := yaml.Unmarshal(b, &ref); err != nil {
+
+	var file activeProvidersFile
+	if err := yaml.Unmarshal(b, &file); err != nil {
 		return nil, err
 	}
-	return &ref, nil
+
+	providers := file.Providers
+	if len(providers) == 0 {
+		// Legacy format: a single provider reference
+		var ref ProviderRef
+		if err := yaml.Unmarshal(b, &ref); err != nil {
+			return nil, err
+		}
+		if ref.Name == "" {
+			return nil, ErrNoActiveProvider
+		}
+		providers = []*ActiveProvider{{ProviderRef: ref}}
+	}
+
+	sort.SliceStable(providers, func(i, j int) bool {
+		return providers[i].Priority > providers[j].Priority
+	})
+	return providers, nil
+}
+
+// ReadActiveProvider returns the highest-priority active provider
+func ReadActiveProvider() (*ProviderRef, error) {
+	providers, err := ReadActiveProviders()
+	if err != nil {
+		return nil, err
+	}
+	return &providers[0].ProviderRef, nil
+}
+
+// IsActiveProvider reports whether ref is one of the active providers
+func IsActiveProvider(ref *ProviderRef) bool {
+	providers, err := ReadActiveProviders()
+	if err != nil {
+		return false
+	}
+	for _, p := range providers {
+		if p.ProviderRef.Matches(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProviderDir returns the install directory for ref, preferring the flat
+// providers/<name> layout used by OCI installs over the nested
+// providers/<namespace>/<name>/<version> layout
+func ProviderDir(ref *ProviderRef) string {
+	flat := filepath.Join(DataHome(), "providers", ref.Name)
+	if _, err := os.Stat(filepath.Join(flat, "thin.provider.yaml")); err == nil {
+		return flat
+	}
+	if _, err := os.Stat(filepath.Join(flat, "bin")); err == nil {
+		return flat
+	}
+	return filepath.Join(DataHome(), "providers", ref.Namespace, ref.Name, ref.Version)
+}
+
+// ErrInvalidProviderName is returned for install names that aren't a single
+// path component
+var ErrInvalidProviderName = errors.New("invalid provider name")
+
+// ValidateProviderName checks that name can be used as the directory of an
+// installed provider: a single path component, not "." or ".."
+func ValidateProviderName(name string) error {
+	if name == "" || name == "." || name == ".." ||
+		strings.ContainsAny(name, `/\`) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return fmt.Errorf("%w: %q", ErrInvalidProviderName, name)
+	}
+	return nil
+}
+
+// installedProviderDir returns the directory of the provider installed as
+// name, making sure it is a direct child of the providers directory
+func installedProviderDir(name string) (string, error) {
+	if err := ValidateProviderName(name); err != nil {
+		return "", err
+	}
+	providers := filepath.Clean(filepath.Join(DataHome(), "providers"))
+	dir := filepath.Clean(filepath.Join(providers, name))
+	if filepath.Dir(dir) != providers {
+		return "", fmt.Errorf("%w: %q", ErrInvalidProviderName, name)
+	}
+	return dir, nil
+}
+
+// RemoveProvider deletes the provider installed as name and returns the
+// directory it was installed in. It waits for any install of the same
+// provider to finish first.
+func RemoveProvider(ctx context.Context, name string) (string, error) {
+	dir, err := installedProviderDir(name)
+	if err != nil {
+		return "", err
+	}
+
+	lock, err := LockProvider(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Unlock()
+
+	if _, err := os.Stat(dir); err != nil {
+		return "", &fs.PathError{Op: "remove provider", Path: dir, Err: fs.ErrNotExist}
+	}
+	if err := removeProviderTree(dir); err != nil {
+		return "", fmt.Errorf("failed to remove provider %s: %w", name, err)
+	}
+	return dir, nil
 }
 
 func ActiveProviderToolsDir() (string, error) {
@@ -63,7 +294,7 @@ func ActiveProviderToolsDir() (string, error) {
 		return "", err
 	}
 	return filepath.Join(
-		ThinHome(),
+		DataHome(),
 		"providers",
 		ref.Namespace,
 		ref.Name,
@@ -73,7 +304,7 @@ func ActiveProviderToolsDir() (string, error) {
 }
 
 func ListProviders() ([]*ProviderRef, error) {
-	providersDir := filepath.Join(ThinHome(), "providers")
+	providersDir := filepath.Join(DataHome(), "providers")
 
 	// Check if providers directory exists
 	if _, err := os.Stat(providersDir); err != nil {
@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseProviderRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want *ProviderRef
+	}{
+		{"acme/ci@v1", &ProviderRef{Namespace: "acme", Name: "ci", Version: "v1"}},
+		{"ghcr.io/acme/ci@1.2.0", &ProviderRef{Registry: "ghcr.io", Namespace: "acme", Name: "ci", Version: "1.2.0"}},
+		{"localhost:5000/org/team/ci@v1", &ProviderRef{Registry: "localhost:5000", Namespace: "org/team", Name: "ci", Version: "v1"}},
+		{"localhost/acme/ci@v1", &ProviderRef{Registry: "localhost", Namespace: "acme", Name: "ci", Version: "v1"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseProviderRef(tt.ref)
+		if err != nil {
+			t.Errorf("ParseProviderRef(%q) error: %v", tt.ref, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseProviderRef(%q) = %+v, want %+v", tt.ref, got, tt.want)
+		}
+		if s := got.String(); s != tt.ref {
+			t.Errorf("ParseProviderRef(%q).String() = %q", tt.ref, s)
+		}
+	}
+}
+
+func TestParseProviderRefInvalid(t *testing.T) {
+	refs := []string{
+		"",
+		"acme/ci",
+		"acme/ci@",
+		"acme/ci@v1@v2",
+		"ci@v1",
+		"org/team/ci@v1",
+		"/ci@v1",
+		"acme/@v1",
+		"../ci@v1",
+		"acme/..@v1",
+		"ghcr.io/acme/./ci@v1",
+		"acme/ci@../v1",
+		`acme/ci@v1\..`,
+		`acme\..\x/ci@v1`,
+	}
+	for _, ref := range refs {
+		if got, err := ParseProviderRef(ref); !errors.Is(err, ErrInvalidProviderRef) {
+			t.Errorf("ParseProviderRef(%q) = %+v, %v, want ErrInvalidProviderRef", ref, got, err)
+		}
+	}
+}
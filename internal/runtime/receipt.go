@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// receiptFile is written to the provider directory at install time
+const receiptFile = ".thin-receipt.json"
+
+// Receipt records what was installed into a provider directory
+type Receipt struct {
+	Name           string            `json:"name"`
+	Source         string            `json:"source"`         // Image reference the provider was installed from
+	ManifestDigest string            `json:"manifestDigest"` // Digest of the OCI manifest
+	InstalledAt    time.Time         `json:"installedAt"`
+	Files          map[string]string `json:"files"` // Relative path -> sha256, or link:<target> for symlinks
+}
+
+// WriteReceipt hashes every file in providerDir and records it together
+// with the install source
+func WriteReceipt(providerDir, name, source, manifestDigest string) error {
+	files, err := hashTree(providerDir, isReceiptPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash provider files: %w", err)
+	}
+
+	receipt := &Receipt{
+		Name:           name,
+		Source:         source,
+		ManifestDigest: manifestDigest,
+		InstalledAt:    time.Now().UTC(),
+		Files:          files,
+	}
+	data, err := json.MarshalIndent(receipt, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(providerDir, receiptFile), data, 0644)
+}
+
+// ReadReceipt reads the install receipt of providerDir
+func ReadReceipt(providerDir string) (*Receipt, error) {
+	data, err := os.ReadFile(filepath.Join(providerDir, receiptFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no install receipt in %s (reinstall the provider to create one)", providerDir)
+		}
+		return nil, err
+	}
+
+	var receipt Receipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		return nil, fmt.Errorf("failed to parse install receipt: %w", err)
+	}
+	return &receipt, nil
+}
+
+// VerifyResult is the outcome of re-hashing an installed provider
+type VerifyResult struct {
+	Dir     string
+	Receipt *Receipt
+	Diff    *TreeDiff
+}
+
+// OK reports whether the installed tree matches its receipt
+func (r *VerifyResult) OK() bool {
+	return r.Diff.Empty()
+}
+
+// VerifyProvider re-hashes providerDir and compares it with its receipt
+func VerifyProvider(providerDir string) (*VerifyResult, error) {
+	receipt, err := ReadReceipt(providerDir)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := hashTree(providerDir, isReceiptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash provider files: %w", err)
+	}
+
+	return &VerifyResult{
+		Dir:     providerDir,
+		Receipt: receipt,
+		Diff:    diffTrees(receipt.Files, current),
+	}, nil
+}
+
+// ProviderTamperedError is returned when strict verification finds an
+// installed provider that no longer matches its receipt
+type ProviderTamperedError struct {
+	Result *VerifyResult
+}
+
+func (e *ProviderTamperedError) Error() string {
+	return fmt.Sprintf("provider %s does not match its install receipt (%s); reinstall it from %s",
+		e.Result.Receipt.Name, e.Result.Diff, e.Result.Receipt.Source)
+}
+
+// VerifyProviderStrict fails unless providerDir matches its receipt
+func VerifyProviderStrict(providerDir string) error {
+	result, err := VerifyProvider(providerDir)
+	if err != nil {
+		return err
+	}
+	if !result.OK() {
+		return &ProviderTamperedError{Result: result}
+	}
+	return nil
+}
+
+func isReceiptPath(rel string) bool {
+	return rel == receiptFile
+}
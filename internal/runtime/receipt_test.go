@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVerifyProvider(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(dir string) error
+		want   TreeDiff
+	}{
+		{
+			name:   "unchanged",
+			change: func(dir string) error { return nil },
+		},
+		{
+			name: "receipt rewritten",
+			change: func(dir string) error {
+				return WriteReceipt(dir, "demo", "registry.example/acme/demo:v1", "sha256:0")
+			},
+		},
+		{
+			name: "modified",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "bin", "entrypoint"), []byte("#!/bin/sh\nexit 1\n"), 0755)
+			},
+			want: TreeDiff{Modified: []string{"bin/entrypoint"}},
+		},
+		{
+			name: "added",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "tools", "extra"), nil, 0755)
+			},
+			want: TreeDiff{Added: []string{"tools/extra"}},
+		},
+		{
+			name: "missing",
+			change: func(dir string) error {
+				return os.Remove(filepath.Join(dir, "tools", "fmt"))
+			},
+			want: TreeDiff{Missing: []string{"tools/fmt"}},
+		},
+		{
+			name: "renamed",
+			change: func(dir string) error {
+				return os.Rename(filepath.Join(dir, "tools", "fmt"), filepath.Join(dir, "tools", "lint"))
+			},
+			want: TreeDiff{Added: []string{"tools/lint"}, Missing: []string{"tools/fmt"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{
+				"thin.provider.yaml": "apiVersion: thin.io/v1\n",
+				"bin/entrypoint":     "#!/bin/sh\n",
+				"tools/fmt":          "#!/bin/sh\n",
+			}
+			for rel, content := range files {
+				path := filepath.Join(dir, filepath.FromSlash(rel))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := WriteReceipt(dir, "demo", "registry.example/acme/demo:v1", "sha256:0"); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.change(dir); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := VerifyProvider(dir)
+			if err != nil {
+				t.Fatalf("VerifyProvider error: %v", err)
+			}
+			if !reflect.DeepEqual(*result.Diff, tt.want) {
+				t.Errorf("VerifyProvider diff = %+v, want %+v", *result.Diff, tt.want)
+			}
+
+			err = VerifyProviderStrict(dir)
+			var tampered *ProviderTamperedError
+			if tt.want.Empty() {
+				if err != nil {
+					t.Errorf("VerifyProviderStrict error: %v", err)
+				}
+			} else if !errors.As(err, &tampered) {
+				t.Errorf("VerifyProviderStrict = %v, want *ProviderTamperedError", err)
+			}
+		})
+	}
+}
+
+func TestVerifyProviderWithoutReceipt(t *testing.T) {
+	if _, err := VerifyProvider(t.TempDir()); err == nil {
+		t.Error("VerifyProvider succeeded without a receipt, want error")
+	}
+}
@@ -0,0 +1,200 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// ErrInvalidImageReference is wrapped by every ReferenceError
+var ErrInvalidImageReference = errors.New("invalid image reference")
+
+// ReferenceError explains why an image reference was rejected
+type ReferenceError struct {
+	Ref    string
+	Reason string
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("invalid image reference %q: %s", e.Ref, e.Reason)
+}
+
+func (e *ReferenceError) Unwrap() error {
+	return ErrInvalidImageReference
+}
+
+// dockerHub is the registry of references without one
+const dockerHub = "docker.io"
+
+// maxNameLength is the longest registry/repository the distribution spec allows
+const maxNameLength = 255
+
+var (
+	hostLabelPattern     = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+	pathComponentPattern = regexp.MustCompile(`^[a-z0-9]+((\.|_|__|-+)[a-z0-9]+)*$`)
+	tagPattern           = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+)
+
+// ImageReference is a parsed OCI image reference:
+//
+//	[registry[:port]/]repository[:tag][@digest]
+//
+// References without a registry are Docker Hub references, and single-name
+// Docker Hub repositories live under library/.
+type ImageReference struct {
+	Registry   string // host with optional port, e.g. "localhost:5000"
+	Repository string // e.g. "library/alpine"
+	Tag        string // "latest" when neither tag nor digest is given
+	Digest     string // e.g. "sha256:...", pins the manifest when set
+}
+
+// ParseImageReference parses and normalizes ref
+func ParseImageReference(ref string) (*ImageReference, error) {
+	fail := func(format string, args ...interface{}) (*ImageReference, error) {
+		return nil, &ReferenceError{Ref: ref, Reason: fmt.Sprintf(format, args...)}
+	}
+	if ref == "" {
+		return fail("empty reference")
+	}
+	if strings.TrimSpace(ref) != ref || strings.ContainsAny(ref, " \t\n") {
+		return fail("contains whitespace")
+	}
+
+	r := &ImageReference{}
+	name := ref
+	if i := strings.Index(name, "@"); i >= 0 {
+		r.Digest = name[i+1:]
+		name = name[:i]
+		if _, err := digest.Parse(r.Digest); err != nil {
+			return fail("invalid digest %q: %v", r.Digest, err)
+		}
+	}
+	// A colon after the last slash starts the tag; before it, a port
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		r.Tag = name[i+1:]
+		name = name[:i]
+		if !tagPattern.MatchString(r.Tag) {
+			return fail("invalid tag %q: tags are up to 128 letters, digits, '_', '.' and '-', and can't start with '.' or '-'", r.Tag)
+		}
+	}
+
+	r.Registry, r.Repository = dockerHub, name
+	if i := strings.Index(name, "/"); i >= 0 && isRegistryHost(name[:i]) {
+		r.Registry, r.Repository = name[:i], name[i+1:]
+		if err := validateRegistry(r.Registry); err != nil {
+			return fail("%v", err)
+		}
+	}
+	if r.Registry == "index.docker.io" {
+		r.Registry = dockerHub
+	}
+	if r.Registry == dockerHub && !strings.Contains(r.Repository, "/") {
+		r.Repository = "library/" + r.Repository
+	}
+
+	if r.Repository == "" {
+		return fail("missing repository")
+	}
+	for _, component := range strings.Split(r.Repository, "/") {
+		switch {
+		case component == "":
+			return fail("empty path component in repository %q", r.Repository)
+		case strings.ToLower(component) != component:
+			return fail("repository %q must be lowercase", r.Repository)
+		case !pathComponentPattern.MatchString(component):
+			return fail("invalid repository path component %q: use lowercase letters and digits separated by '.', '_', '__' or '-'", component)
+		}
+	}
+	if n := len(r.Name()); n > maxNameLength {
+		return fail("name is %d characters, longer than %d", n, maxNameLength)
+	}
+
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+	return r, nil
+}
+
+// isRegistryHost reports whether the first component of a name is a
+// registry rather than part of a Docker Hub repository path
+func isRegistryHost(component string) bool {
+	return strings.ContainsAny(component, ".:[") || component == "localhost"
+}
+
+// validateRegistry checks a registry host and optional port
+func validateRegistry(registry string) error {
+	host, port := registry, ""
+	if strings.HasPrefix(host, "[") {
+		// IPv6 literal
+		end := strings.Index(host, "]")
+		if end < 0 {
+			return fmt.Errorf("unterminated IPv6 address in registry %q", registry)
+		}
+		host, port = host[:end+1], strings.TrimPrefix(host[end+1:], ":")
+		if host == "[]" {
+			return fmt.Errorf("empty IPv6 address in registry %q", registry)
+		}
+	} else if i := strings.LastIndex(host, ":"); i >= 0 {
+		host, port = host[:i], host[i+1:]
+		if port == "" {
+			return fmt.Errorf("empty port in registry %q", registry)
+		}
+	}
+
+	if port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid port %q in registry %q", port, registry)
+		}
+	}
+	if strings.HasPrefix(host, "[") {
+		return nil
+	}
+	for _, label := range strings.Split(host, ".") {
+		if !hostLabelPattern.MatchString(label) {
+			return fmt.Errorf("invalid registry host %q", host)
+		}
+	}
+	return nil
+}
+
+// Name returns registry/repository
+func (r *ImageReference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// Reference returns what to resolve in the repository: the digest when the
+// reference is pinned, otherwise the tag
+func (r *ImageReference) Reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// String formats the normalized reference
+func (r *ImageReference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// WithName returns a copy of r pulled from name (registry/repository)
+// instead, e.g. a mirror of the same repository
+func (r *ImageReference) WithName(name string) *ImageReference {
+	c := *r
+	c.Registry, c.Repository = name, ""
+	if i := strings.Index(name, "/"); i >= 0 {
+		c.Registry, c.Repository = name[:i], name[i+1:]
+	}
+	return &c
+}
@@ -0,0 +1,134 @@
+package runtime
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// newRepository connects to the repository named by ref, using the
+// settings configured for its registry
+func newRepository(ref string) (*remote.Repository, error) {
+	repo, err := remote.NewRepository(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %s: %w", ref, err)
+	}
+	settings := registryConfig(repo.Reference.Registry)
+	repo.PlainHTTP = settings.PlainHTTP
+
+	transport, err := newTransport(repo.Reference.Registry, settings)
+	if err != nil {
+		return nil, err
+	}
+	// No Client.Timeout: it kills in-flight body reads
+	repo.Client = &auth.Client{
+		Client: &http.Client{Transport: &retryTransport{base: transport, config: retry}},
+		Cache:  auth.NewCache(),
+	}
+
+	return repo, nil
+}
+
+// newTransport builds the HTTP transport for a registry: proxies from
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY, plus the registry's TLS settings
+func newTransport(host string, settings RegistryConfig) (*http.Transport, error) {
+	tlsConfig, err := newTLSConfig(host, settings)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		MaxIdleConnsPerHost:   4,
+		MaxConnsPerHost:       8,
+		IdleConnTimeout:       30 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		ExpectContinueTimeout: 5 * time.Second,
+		WriteBufferSize:       256 * 1024,
+		ReadBufferSize:        256 * 1024,
+		TLSClientConfig:       tlsConfig,
+	}, nil
+}
+
+// newTLSConfig applies a registry's CA bundle, client certificate and
+// insecure setting
+func newTLSConfig(host string, settings RegistryConfig) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: settings.Insecure,
+	}
+
+	if settings.CA != "" {
+		pem, err := os.ReadFile(settings.CA)
+		if err != nil {
+			return nil, fmt.Errorf("registry %s: failed to read CA bundle: %w", host, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("registry %s: no PEM certificates in CA bundle %s", host, settings.CA)
+		}
+		config.RootCAs = pool
+	}
+
+	switch {
+	case settings.Cert != "" && settings.Key != "":
+		cert, err := tls.LoadX509KeyPair(settings.Cert, settings.Key)
+		if err != nil {
+			return nil, fmt.Errorf("registry %s: failed to load client certificate: %w", host, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	case settings.Cert != "" || settings.Key != "":
+		return nil, fmt.Errorf("registry %s: cert and key must be set together", host)
+	}
+
+	return config, nil
+}
+
+// ListTags returns all tags of the repository at ref (registry/repository,
+// no tag), from the first mirror or registry that answers
+func ListTags(ctx context.Context, ref string) ([]string, error) {
+	parsed, err := ParseImageReference(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, source := range pullSources(parsed.Name()) {
+		tags, err := listTags(ctx, source.Repository)
+		if err == nil {
+			return tags, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+func listTags(ctx context.Context, ref string) ([]string, error) {
+	repo, err := newRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	err = repo.Tags(ctx, "", func(page []string) error {
+		tags = append(tags, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for %s: %w", ref, err)
+	}
+	return tags, nil
+}
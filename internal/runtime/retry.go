@@ -0,0 +1,356 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// RetryConfig controls how failed registry requests are retried
+type RetryConfig struct {
+	// Attempts is the number of tries per request, including the first.
+	// 1 disables retries. Default 5.
+	Attempts int `yaml:"attempts"`
+
+	// InitialDelay is the wait before the first retry; it doubles on each
+	// retry. Default 500ms.
+	InitialDelay time.Duration `yaml:"initialDelay"`
+
+	// MaxDelay caps the backoff and Retry-After waits. Default 30s.
+	MaxDelay time.Duration `yaml:"maxDelay"`
+}
+
+// withDefaults fills unset fields
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.Attempts <= 0 {
+		c.Attempts = 5
+	}
+	if c.InitialDelay <= 0 {
+		c.InitialDelay = 500 * time.Millisecond
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = 30 * time.Second
+	}
+	return c
+}
+
+// backoff returns the wait before retry number n (1-based): exponential
+// with jitter between half and all of the nominal delay
+func (c RetryConfig) backoff(n int) time.Duration {
+	d := c.InitialDelay << uint(n-1)
+	if d <= 0 || d > c.MaxDelay {
+		d = c.MaxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retry is set from config by ConfigureRegistries
+var retry = RetryConfig{}.withDefaults()
+
+// retryTransport retries idempotent requests that fail with a transient
+// network error or a 408, 429, 500, 502, 503 or 504 response
+type retryTransport struct {
+	base   http.RoundTripper
+	config RetryConfig
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return t.base.RoundTrip(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.config.Attempts || req.Context().Err() != nil || !retryableResponse(resp, err) {
+			return resp, err
+		}
+
+		delay := t.config.backoff(attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if d, ok := retryAfter(resp); ok {
+				delay = d
+				if delay > t.config.MaxDelay {
+					delay = t.config.MaxDelay
+				}
+			}
+			// Drain so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+
+		trace.SpanFromContext(req.Context()).AddEvent("thin.retry", trace.WithAttributes(
+			attribute.String("http.url", req.URL.Redacted()),
+			attribute.String("thin.retry.reason", reason),
+			attribute.Int("thin.retry.attempt", attempt),
+			attribute.Int64("thin.retry.delay_ms", delay.Milliseconds()),
+		))
+		statusf("⚠ %s %s: %s, retrying in %s (attempt %d of %d)",
+			req.Method, req.URL.Path, reason, formatDuration(delay), attempt+1, t.config.Attempts)
+
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryableResponse reports whether a request that ended with resp or err
+// may succeed when tried again
+func retryableResponse(resp *http.Response, err error) bool {
+	if err != nil {
+		return transientError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// transientError reports whether err is a network failure worth retrying.
+// Refused connections and DNS failures are not: they rarely clear up within
+// the retry window, and mirrors should fall back quickly.
+func transientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNABORTED) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryAfter parses the Retry-After header of a 429 or 503 response,
+// given either in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		d := time.Until(at)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resumableStorage makes blob reads from a repository survive dropped
+// connections: a body that fails part way is re-requested from the byte
+// it stopped at with a Range request
+type resumableStorage struct {
+	*remote.Repository
+}
+
+func (s *resumableStorage) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	rc, err := s.Repository.Fetch(ctx, desc)
+	if err != nil || isManifestType(desc.MediaType) {
+		// Manifests are small and fetched from a different endpoint
+		return rc, err
+	}
+	return &resumingReader{
+		ctx:  ctx,
+		rc:   rc,
+		desc: desc,
+		fetch: func(offset int64) (io.ReadCloser, error) {
+			return fetchBlobRange(ctx, s.Repository, desc, offset)
+		},
+	}, nil
+}
+
+var _ content.ReadOnlyStorage = (*resumableStorage)(nil)
+
+// isManifestType reports whether mediaType is an OCI or Docker manifest or index
+func isManifestType(mediaType string) bool {
+	switch mediaType {
+	case ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex,
+		"application/vnd.docker.distribution.manifest.v2+json",
+		"application/vnd.docker.distribution.manifest.list.v2+json":
+		return true
+	}
+	return false
+}
+
+// resumingReader reads a blob, resuming from its current offset when the
+// stream fails with a transient error
+type resumingReader struct {
+	ctx     context.Context
+	rc      io.ReadCloser
+	desc    ocispec.Descriptor
+	offset  int64
+	resumes int
+	fetch   func(offset int64) (io.ReadCloser, error)
+}
+
+func (r *resumingReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.rc.Read(p)
+		r.offset += int64(n)
+		if err == nil || (err == io.EOF && r.offset >= r.desc.Size) {
+			return n, err
+		}
+		if err == io.EOF {
+			// The server closed the stream before the end of the blob
+			err = io.ErrUnexpectedEOF
+		}
+		if !transientError(err) || r.resumes+1 >= retry.Attempts || r.ctx.Err() != nil {
+			return n, err
+		}
+		if n > 0 {
+			// Deliver what we have; the next Read resumes
+			return n, r.resume(err)
+		}
+		if rerr := r.resume(err); rerr != nil {
+			return 0, rerr
+		}
+	}
+}
+
+// resume waits and re-requests the blob from the current offset
+func (r *resumingReader) resume(cause error) error {
+	r.resumes++
+	r.rc.Close()
+
+	delay := retry.backoff(r.resumes)
+	statusf("⚠ %s interrupted at %s of %s (%v), resuming in %s",
+		shortDigest(r.desc), formatBytes(r.offset), formatBytes(r.desc.Size), cause, formatDuration(delay))
+	trace.SpanFromContext(r.ctx).AddEvent("thin.resume", trace.WithAttributes(
+		attribute.String("oci.digest", r.desc.Digest.String()),
+		attribute.Int64("thin.resume.offset", r.offset),
+		attribute.String("thin.resume.reason", cause.Error()),
+	))
+	if err := sleepContext(r.ctx, delay); err != nil {
+		return err
+	}
+
+	rc, err := r.fetch(r.offset)
+	if err != nil {
+		return fmt.Errorf("failed to resume %s at offset %d: %w", shortDigest(r.desc), r.offset, err)
+	}
+	r.rc = rc
+	return nil
+}
+
+func (r *resumingReader) Close() error {
+	return r.rc.Close()
+}
+
+// fetchBlobRange requests desc from repo starting at offset. Only a 206
+// for exactly that range is used as is. Servers that ignore the Range
+// header, or answer with another range, have the whole blob fetched
+// instead, and the bytes before offset, which were already read, are
+// skipped.
+func fetchBlobRange(ctx context.Context, repo *remote.Repository, desc ocispec.Descriptor, offset int64) (io.ReadCloser, error) {
+	ref := repo.Reference
+	ref.Reference = desc.Digest.String()
+	ctx = auth.AppendRepositoryScope(ctx, ref, auth.ActionPull)
+
+	scheme := "https"
+	if repo.PlainHTTP {
+		scheme = "http"
+	}
+	url := fmt.Sprintf("%s://%s/v2/%s/blobs/%s", scheme, ref.Host(), ref.Repository, ref.Reference)
+
+	resp, err := getBlob(ctx, repo, url, offset)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusPartialContent {
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); ok && start == offset {
+			return resp.Body, nil
+		}
+		resp.Body.Close()
+		if resp, err = getBlob(ctx, repo, url, 0); err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("GET %s: unexpected status %s for the whole blob", url, resp.Status)
+		}
+	}
+
+	if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// getBlob requests the blob at url from offset on, or all of it when offset
+// is 0. Responses other than 200 and 206 are errors.
+func getBlob(ctx context.Context, repo *remote.Repository, url string, offset int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := repo.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: unexpected status %s", req.Method, url, resp.Status)
+	}
+	return resp, nil
+}
+
+// contentRangeStart returns the first byte position of a Content-Range
+// header such as "bytes 100-199/200"
+func contentRangeStart(header string) (int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	return start, err == nil && start >= 0
+}
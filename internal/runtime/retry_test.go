@@ -0,0 +1,234 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+func TestFetchBlobRange(t *testing.T) {
+	const blob = "0123456789abcdefghij"
+	const offset = 7
+	desc := ocispec.Descriptor{Digest: digest.FromString(blob), Size: int64(len(blob))}
+
+	tests := []struct {
+		name string
+		// respond answers a request for the blob; rangeStart is -1 without
+		// a Range header
+		respond func(w http.ResponseWriter, rangeStart int)
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "range honoured",
+			respond: func(w http.ResponseWriter, rangeStart int) {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", rangeStart, len(blob)-1, len(blob)))
+				w.WriteHeader(http.StatusPartialContent)
+				io.WriteString(w, blob[rangeStart:])
+			},
+			want: blob[offset:],
+		},
+		{
+			name: "range ignored",
+			respond: func(w http.ResponseWriter, rangeStart int) {
+				io.WriteString(w, blob)
+			},
+			want: blob[offset:],
+		},
+		{
+			name: "other range",
+			respond: func(w http.ResponseWriter, rangeStart int) {
+				if rangeStart < 0 {
+					io.WriteString(w, blob)
+					return
+				}
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(blob)-1, len(blob)))
+				w.WriteHeader(http.StatusPartialContent)
+				io.WriteString(w, blob)
+			},
+			want: blob[offset:],
+		},
+		{
+			name: "missing content range",
+			respond: func(w http.ResponseWriter, rangeStart int) {
+				if rangeStart < 0 {
+					io.WriteString(w, blob)
+					return
+				}
+				w.WriteHeader(http.StatusPartialContent)
+				io.WriteString(w, blob[rangeStart:])
+			},
+			want: blob[offset:],
+		},
+		{
+			name: "partial content for the whole blob",
+			respond: func(w http.ResponseWriter, rangeStart int) {
+				w.Header().Set("Content-Range", "bytes 3-19/20")
+				w.WriteHeader(http.StatusPartialContent)
+				io.WriteString(w, blob[3:])
+			},
+			wantErr: true,
+		},
+		{
+			name: "error status",
+			respond: func(w http.ResponseWriter, rangeStart int) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rangeStart := -1
+				if h := r.Header.Get("Range"); h != "" {
+					fmt.Sscanf(h, "bytes=%d-", &rangeStart)
+				}
+				tt.respond(w, rangeStart)
+			}))
+			defer server.Close()
+
+			repo, err := remote.NewRepository(strings.TrimPrefix(server.URL, "http://") + "/acme/demo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			repo.PlainHTTP = true
+			repo.Client = server.Client()
+
+			rc, err := fetchBlobRange(context.Background(), repo, desc, offset)
+			if tt.wantErr {
+				if err == nil {
+					rc.Close()
+					t.Fatal("fetchBlobRange succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchBlobRange error: %v", err)
+			}
+			defer rc.Close()
+			got, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("fetchBlobRange read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentRangeStart(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int64
+		ok     bool
+	}{
+		{"bytes 100-199/200", 100, true},
+		{"bytes 0-9/*", 0, true},
+		{"bytes */200", 0, false},
+		{"items 1-2/3", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := contentRangeStart(tt.header)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("contentRangeStart(%q) = %d, %v, want %d, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		status int
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{http.StatusTooManyRequests, "3", 3 * time.Second, true},
+		{http.StatusServiceUnavailable, "0", 0, true},
+		{http.StatusServiceUnavailable, time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+		{http.StatusTooManyRequests, "-1", 0, false},
+		{http.StatusTooManyRequests, "soon", 0, false},
+		{http.StatusTooManyRequests, "", 0, false},
+		{http.StatusInternalServerError, "3", 0, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		got, ok := retryAfter(resp)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%d, %q) = %s, %v, want %s, %v", tt.status, tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if got, ok := retryAfter(resp); !ok || got <= 0 || got > time.Minute {
+		t.Errorf("retryAfter(HTTP date a minute away) = %s, %v, want up to a minute", got, ok)
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	SetMessageOutput(io.Discard)
+	defer SetMessageOutput(os.Stdout)
+
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int // answered in turn; the last one repeats
+		want     int
+		requests int
+	}{
+		{"success", http.MethodGet, []int{200}, 200, 1},
+		{"retried until success", http.MethodGet, []int{503, 502, 200}, 200, 3},
+		{"rate limited", http.MethodHead, []int{429, 200}, 200, 2},
+		{"not found isn't retried", http.MethodGet, []int{404, 200}, 404, 1},
+		{"post isn't retried", http.MethodPost, []int{503, 200}, 503, 1},
+		{"attempts exhausted", http.MethodGet, []int{500}, 500, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[min(requests, len(tt.statuses)-1)]
+				requests++
+				if status == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "0")
+				}
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &retryTransport{
+				base:   http.DefaultTransport,
+				config: RetryConfig{Attempts: 3, InitialDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+			}}
+			req, err := http.NewRequest(tt.method, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("request error: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.want || requests != tt.requests {
+				t.Errorf("status %d after %d requests, want %d after %d", resp.StatusCode, requests, tt.want, tt.requests)
+			}
+		})
+	}
+}
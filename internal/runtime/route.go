@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoRoute is returned when no active provider defines a command
+var ErrNoRoute = errors.New("no active provider defines this command")
+
+// Route describes where a tool or capability name dispatches to
+type Route struct {
+	Provider *ActiveProvider
+	// ToolPath is set when the name is a tool binary under tools/;
+	// it is empty when the name is a capability of the provider entrypoint
+	ToolPath string
+}
+
+// AmbiguousCommandError is returned when several active providers with the
+// same priority define the same command
+type AmbiguousCommandError struct {
+	Command   string
+	Providers []*ActiveProvider
+}
+
+func (e *AmbiguousCommandError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "'%s' is defined by multiple active providers:\n", e.Command)
+	for _, p := range e.Providers {
+		fmt.Fprintf(&b, "  %s (priority %d)\n", p.ProviderRef.String(), p.Priority)
+	}
+	first := e.Providers[0].ProviderRef
+	fmt.Fprintf(&b, "Qualify the call, e.g. `thin %s %s`, or raise one provider's priority with `thin provider use --add --priority <n> <ref>`",
+		first.String(), e.Command)
+	return b.String()
+}
+
+// RouteCommand finds the active provider that defines name as a tool or
+// capability. When several do, the highest priority wins; a tie is reported
+// as an *AmbiguousCommandError.
+func RouteCommand(name string) (*Route, error) {
+	providers, err := ReadActiveProviders()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*Route
+	for _, p := range providers {
+		if route := routeInProvider(name, p); route != nil {
+			matches = append(matches, route)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, ErrNoRoute
+	}
+
+	// Providers are sorted by priority, so ties are at the front
+	var tied []*ActiveProvider
+	for _, m := range matches {
+		if m.Provider.Priority == matches[0].Provider.Priority {
+			tied = append(tied, m.Provider)
+		}
+	}
+	if len(tied) > 1 {
+		return nil, &AmbiguousCommandError{Command: name, Providers: tied}
+	}
+
+	return matches[0], nil
+}
+
+// isToolName reports whether name can name a tool binary: a single path
+// component without "..", so it can't reach outside tools/
+func isToolName(name string) bool {
+	return name != "" && name != "." && !strings.Contains(name, "..") && !strings.ContainsAny(name, `/\`)
+}
+
+// routeInProvider checks whether p defines name, either as a tool binary
+// or as a manifest capability
+func routeInProvider(name string, p *ActiveProvider) *Route {
+	providerDir := ProviderDir(&p.ProviderRef)
+
+	if isToolName(name) {
+		toolPath := filepath.Join(providerDir, "tools", name)
+		if info, err := os.Stat(toolPath); err == nil && !info.IsDir() {
+			return &Route{Provider: p, ToolPath: toolPath}
+		}
+	}
+
+	manifest, err := ReadProviderManifest(providerDir)
+	if err != nil || manifest == nil {
+		return nil
+	}
+	if _, ok := manifest.Capabilities[name]; ok {
+		return &Route{Provider: p}
+	}
+	return nil
+}
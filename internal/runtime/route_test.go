@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRouteCommand(t *testing.T) {
+	SetMessageOutput(io.Discard)
+	defer SetMessageOutput(os.Stdout)
+
+	tests := []struct {
+		name       string
+		command    string
+		priorities map[string]int // active provider name to priority
+		want       string         // routed provider, empty for an error
+		wantTool   bool
+		wantErr    error
+		ambiguous  []string
+	}{
+		{name: "capability", command: "run", priorities: map[string]int{"lint": 0}, want: "lint"},
+		{name: "tool", command: "fmt", priorities: map[string]int{"lint": 0, "deploy": 0}, want: "lint", wantTool: true},
+		{name: "higher priority wins", command: "run", priorities: map[string]int{"lint": 1, "deploy": 5}, want: "deploy"},
+		{name: "tie", command: "run", priorities: map[string]int{"lint": 2, "deploy": 2, "docs": 1}, ambiguous: []string{"deploy", "lint"}},
+		{name: "tie below the winner", command: "run", priorities: map[string]int{"lint": 2, "deploy": 2, "docs": 3}, want: "docs"},
+		{name: "unknown", command: "publish", priorities: map[string]int{"lint": 0}, wantErr: ErrNoRoute},
+		{name: "no active providers", command: "run", wantErr: ErrNoActiveProvider},
+		{name: "tool outside tools/", command: "../entrypoint", priorities: map[string]int{"lint": 0}, wantErr: ErrNoRoute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTempHomes(t)
+			for _, name := range []string{"lint", "deploy", "docs"} {
+				installTestProvider(t, name, "1.0.0", "registry.example/acme/"+name+":v1.0.0")
+			}
+			tools := filepath.Join(DataHome(), "providers", "lint", "tools")
+			if err := os.MkdirAll(tools, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(tools, "fmt"), []byte("#!/bin/sh\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			for name, priority := range tt.priorities {
+				ref := &ProviderRef{Namespace: "acme", Name: name, Version: "1.0.0"}
+				if err := AddActiveProvider(ref, priority); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			route, err := RouteCommand(tt.command)
+			var ambiguous *AmbiguousCommandError
+			switch {
+			case tt.ambiguous != nil:
+				if !errors.As(err, &ambiguous) {
+					t.Fatalf("RouteCommand(%q) = %v, %v, want *AmbiguousCommandError", tt.command, route, err)
+				}
+				var got []string
+				for _, p := range ambiguous.Providers {
+					got = append(got, p.Name)
+				}
+				sort.Strings(got)
+				if !reflect.DeepEqual(got, tt.ambiguous) {
+					t.Errorf("ambiguous between %v, want %v", got, tt.ambiguous)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("RouteCommand(%q) = %v, %v, want %v", tt.command, route, err, tt.wantErr)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("RouteCommand(%q) error: %v", tt.command, err)
+				}
+				if route.Provider.Name != tt.want || (route.ToolPath != "") != tt.wantTool {
+					t.Errorf("RouteCommand(%q) = %s (tool %q), want %s (tool %v)",
+						tt.command, route.Provider.Name, route.ToolPath, tt.want, tt.wantTool)
+				}
+			}
+		})
+	}
+}
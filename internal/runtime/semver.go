@@ -0,0 +1,262 @@
+package runtime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semVersion is a parsed semantic version (major.minor.patch[-prerelease])
+// Build metadata is ignored.
+type semVersion struct {
+	Major, Minor, Patch int
+	Prerelease          string
+}
+
+// parseSemVersion parses versions such as "1.2.3", "v1.2" or "1.2.3-rc.1"
+// Missing minor/patch components default to zero.
+func parseSemVersion(s string) (*semVersion, error) {
+	v := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+
+	var pre string
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		v, pre = v[:i], v[i+1:]
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) == 0 || len(parts) > 3 || parts[0] == "" {
+		return nil, fmt.Errorf("invalid version: %s", s)
+	}
+
+	nums := make([]int, 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version: %s", s)
+		}
+		nums[i] = n
+	}
+
+	return &semVersion{Major: nums[0], Minor: nums[1], Patch: nums[2], Prerelease: pre}, nil
+}
+
+func (v *semVersion) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// compare returns -1, 0 or 1. A prerelease sorts before its release;
+// prereleases are compared as in SemVer §11 (see comparePrerelease).
+func (v *semVersion) compare(o *semVersion) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	default:
+		return comparePrerelease(v.Prerelease, o.Prerelease)
+	}
+}
+
+// comparePrerelease compares dot-separated prerelease identifiers from left
+// to right: numeric identifiers numerically, others lexically in ASCII
+// order, and numeric ones before alphanumeric ones. When all identifiers
+// are equal, the one with fewer identifiers sorts first (rc < rc.1).
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareIdentifier(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+func compareIdentifier(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && bNum:
+		// Compare by length first so numbers of any size work
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// versionComponents returns how many of major, minor and patch s spells out
+func versionComponents(s string) int {
+	v := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	return strings.Count(v, ".") + 1
+}
+
+// versionConstraint is a set of comparators that must all hold
+type versionConstraint struct {
+	raw         string
+	comparators []comparator
+}
+
+type comparator struct {
+	op      string // =, !=, >, >=, <, <=
+	version *semVersion
+}
+
+// parseVersionConstraint parses constraints such as ">=1.2.0 <2.0.0",
+// "^1.2", "~1.4.0", "1.x" or "*". Comparators are separated by spaces or commas.
+// An empty constraint matches any version.
+func parseVersionConstraint(s string) (*versionConstraint, error) {
+	c := &versionConstraint{raw: s}
+
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }) {
+		comps, err := parseComparator(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		c.comparators = append(c.comparators, comps...)
+	}
+	return c, nil
+}
+
+// parseComparator expands a single constraint term into comparators
+func parseComparator(term string) ([]comparator, error) {
+	if term == "*" || term == "x" || term == "latest" {
+		return nil, nil
+	}
+
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "="} {
+		if strings.HasPrefix(term, op) {
+			v, err := parseSemVersion(term[len(op):])
+			if err != nil {
+				return nil, err
+			}
+			return []comparator{{op, v}}, nil
+		}
+	}
+
+	switch term[0] {
+	case '^':
+		// ^1.2.3 := >=1.2.3 <2.0.0, ^0.2.3 := >=0.2.3 <0.3.0 and
+		// ^0.0.3 := =0.0.3: the leftmost non-zero component is fixed.
+		// Components left out are free (^0.0 := >=0.0.0 <0.1.0).
+		v, err := parseSemVersion(term[1:])
+		if err != nil {
+			return nil, err
+		}
+		n := versionComponents(term[1:])
+		switch {
+		case v.Major != 0 || n == 1:
+			return []comparator{{">=", v}, {"<", &semVersion{Major: v.Major + 1}}}, nil
+		case v.Minor != 0 || n == 2:
+			return []comparator{{">=", v}, {"<", &semVersion{Minor: v.Minor + 1}}}, nil
+		case v.Prerelease != "":
+			return []comparator{{">=", v}, {"<", &semVersion{Patch: v.Patch + 1}}}, nil
+		}
+		return []comparator{{"=", v}}, nil
+	case '~':
+		// ~1.2.3 := >=1.2.3 <1.3.0
+		v, err := parseSemVersion(term[1:])
+		if err != nil {
+			return nil, err
+		}
+		return []comparator{{">=", v}, {"<", &semVersion{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	}
+
+	// Wildcards: 1.x, 1.2.x, 1.2.*
+	trimmed := strings.TrimPrefix(term, "v")
+	parts := strings.Split(trimmed, ".")
+	if last := parts[len(parts)-1]; last == "x" || last == "*" {
+		v, err := parseSemVersion(strings.Join(parts[:len(parts)-1], "."))
+		if err != nil {
+			return nil, err
+		}
+		upper := &semVersion{Major: v.Major + 1}
+		if len(parts) == 3 {
+			upper = &semVersion{Major: v.Major, Minor: v.Minor + 1}
+		}
+		return []comparator{{">=", v}, {"<", upper}}, nil
+	}
+
+	v, err := parseSemVersion(term)
+	if err != nil {
+		return nil, err
+	}
+	return []comparator{{"=", v}}, nil
+}
+
+// Check reports whether v satisfies every comparator
+func (c *versionConstraint) Check(v *semVersion) bool {
+	for _, comp := range c.comparators {
+		cmp := v.compare(comp.version)
+		ok := false
+		switch comp.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *versionConstraint) String() string {
+	if c.raw == "" {
+		return "*"
+	}
+	return c.raw
+}
@@ -0,0 +1,108 @@
+package runtime
+
+import "testing"
+
+func TestSemVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"v1.2", "1.2.0", 0},
+		{"1.0.0+build.1", "1.0.0", 0},
+		{"1.0.0", "2.0.0", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"1.0.10", "1.0.9", 1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-rc.2", "1.0.0-rc.10", -1},
+		{"1.0.0-rc.10", "1.0.0-rc.2", 1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-beta.11", "1.0.0-rc.1", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+		{"1.0.0-99999999999999999999", "1.0.0-100000000000000000000", -1},
+		{"1.0.0-rc.1", "1.0.0-rc.1", 0},
+	}
+	for _, tt := range tests {
+		a, err := parseSemVersion(tt.a)
+		if err != nil {
+			t.Fatalf("parseSemVersion(%q): %v", tt.a, err)
+		}
+		b, err := parseSemVersion(tt.b)
+		if err != nil {
+			t.Fatalf("parseSemVersion(%q): %v", tt.b, err)
+		}
+		if got := a.compare(b); got != tt.want {
+			t.Errorf("compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := b.compare(a); got != -tt.want {
+			t.Errorf("compare(%s, %s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestVersionConstraintCheck(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"", "0.0.1", true},
+		{"*", "3.1.4", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.3", "1.2.4", false},
+		{">=1.2.0 <2.0.0", "1.9.9", true},
+		{">=1.2.0, <2.0.0", "2.0.0", false},
+		{"!=1.2.3", "1.2.3", false},
+
+		{"^1.2.3", "1.2.3", true},
+		{"^1.2.3", "1.9.0", true},
+		{"^1.2.3", "1.2.2", false},
+		{"^1.2.3", "2.0.0", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.3", true},
+		{"^0.0.3", "0.0.4", false},
+		{"^0.0.3", "0.0.2", false},
+		{"^0.0.3-rc.1", "0.0.3", true},
+		{"^0.0.3-rc.1", "0.0.4", false},
+		{"^0.0", "0.0.9", true},
+		{"^0.0", "0.1.0", false},
+		{"^0", "0.9.0", true},
+		{"^0", "1.0.0", false},
+		{"^1.2", "1.3.0", true},
+
+		{"~1.4.0", "1.4.7", true},
+		{"~1.4.0", "1.5.0", false},
+		{"1.x", "1.9.0", true},
+		{"1.x", "2.0.0", false},
+		{"1.2.x", "1.2.5", true},
+		{"1.2.*", "1.3.0", false},
+
+		{">=1.0.0-rc.2", "1.0.0-rc.10", true},
+		{"<1.0.0-rc.10", "1.0.0-rc.2", true},
+	}
+	for _, tt := range tests {
+		c, err := parseVersionConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("parseVersionConstraint(%q): %v", tt.constraint, err)
+		}
+		v, err := parseSemVersion(tt.version)
+		if err != nil {
+			t.Fatalf("parseSemVersion(%q): %v", tt.version, err)
+		}
+		if got := c.Check(v); got != tt.want {
+			t.Errorf("%q.Check(%s) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersionConstraintErrors(t *testing.T) {
+	for _, s := range []string{"^", "~x", ">=1.a", "1.2.3.4", "abc"} {
+		if _, err := parseVersionConstraint(s); err == nil {
+			t.Errorf("parseVersionConstraint(%q) succeeded, want error", s)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// SignalError is the cause of a context cancelled by NotifySignals
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("stopped by signal: %s", e.Signal)
+}
+
+// NotifySignals returns a context that is cancelled with a *SignalError
+// cause when thin receives SIGINT, SIGTERM or SIGHUP, so that a running
+// provider can be handed the signal instead of being orphaned. Only the
+// first signal is caught: a second one terminates thin as usual. stop
+// releases the signal handler.
+func NotifySignals(ctx context.Context) (_ context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			cancel(&SignalError{Signal: sig})
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel(nil)
+	}
+}
+
+// signalCause returns the signal ctx was cancelled by, if any
+func signalCause(ctx context.Context) (os.Signal, bool) {
+	if err, ok := context.Cause(ctx).(*SignalError); ok {
+		return err.Signal, true
+	}
+	return nil, false
+}
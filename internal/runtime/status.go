@@ -2,15 +2,61 @@ package runtime
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/term"
 )
 
+// messageOutput receives human-readable progress and status messages
+var messageOutput io.Writer = os.Stdout
+
+// SetMessageOutput redirects progress and status messages, e.g. to stderr
+// when stdout carries machine-readable output
+func SetMessageOutput(w io.Writer) {
+	messageOutput = w
+}
+
+// Progress display modes for --progress
+const (
+	ProgressAuto  = "auto"  // tty on a terminal, plain otherwise
+	ProgressTTY   = "tty"   // live progress bars
+	ProgressPlain = "plain" // one line per event
+	ProgressJSON  = "json"  // newline-delimited json events
+	ProgressNone  = "none"  // status messages only
+)
+
+// progressMode is the display mode used by NewStatusHandler
+var progressMode = ProgressAuto
+
+// ConfigureProgress sets how pulls report progress. quiet suppresses
+// progress and status messages altogether. In json mode status messages are
+// emitted as message events, so the stream stays valid NDJSON.
+func ConfigureProgress(mode string, quiet bool) error {
+	switch mode {
+	case ProgressAuto, ProgressTTY, ProgressPlain, ProgressJSON, ProgressNone:
+	default:
+		return fmt.Errorf("invalid progress mode %q (expected %s, %s, %s, %s or %s)",
+			mode, ProgressAuto, ProgressTTY, ProgressPlain, ProgressJSON, ProgressNone)
+	}
+
+	progressMode = mode
+	switch {
+	case quiet:
+		progressMode = ProgressNone
+		messageOutput = io.Discard
+	case mode == ProgressJSON:
+		messageOutput = newEventWriter(messageOutput)
+	}
+	return nil
+}
+
 // StatusHandler handles progress display during artifact pulling
 // Mirrors ORAS CLI behavior for status display
 type StatusHandler interface {
@@ -19,7 +65,13 @@ type StatusHandler interface {
 	OnNodeProcessing(desc ocispec.Descriptor)
 	OnNodeRestored(desc ocispec.Descriptor)
 	OnNodeSkipped(desc ocispec.Descriptor)
+	// UpdateProgress records the bytes read so far for the node with the
+	// given digest
 	UpdateProgress(digest string, bytesRead int64)
+	// Message prints a status line without corrupting the progress display
+	Message(format string, args ...interface{})
+	// Close stops the progress display and prints the pull summary.
+	// It is safe to call more than once.
 	Close()
 }
 
@@ -35,6 +87,80 @@ type NodeProgress struct {
 	LastSpeedRead int64
 }
 
+// Speed returns the average download speed of the node in bytes per second
+func (p *NodeProgress) Speed() float64 {
+	end := p.EndTime
+	if end.IsZero() {
+		end = time.Now()
+	}
+	elapsed := end.Sub(p.StartTime).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.BytesRead) / elapsed
+}
+
+// ETA estimates the time left to download the node at its current speed.
+// Returns false when there is not enough data for an estimate.
+func (p *NodeProgress) ETA() (time.Duration, bool) {
+	speed := p.Speed()
+	if speed <= 0 || p.Descriptor.Size <= 0 {
+		return 0, false
+	}
+	left := p.Descriptor.Size - p.BytesRead
+	if left < 0 {
+		left = 0
+	}
+	return time.Duration(float64(left) / speed * float64(time.Second)), true
+}
+
+// shortDigest is the digest prefix shown in progress output
+func shortDigest(desc ocispec.Descriptor) string {
+	d := desc.Digest.String()
+	if len(d) > 16 {
+		return d[:16]
+	}
+	return d
+}
+
+// pullStats accumulates the totals shown in the pull summary
+type pullStats struct {
+	start           time.Time
+	downloaded      int
+	downloadedBytes int64
+	cached          int
+	cachedBytes     int64
+}
+
+func (s *pullStats) addDownloaded(size int64) {
+	s.downloaded++
+	s.downloadedBytes += size
+}
+
+func (s *pullStats) addCached(size int64) {
+	s.cached++
+	s.cachedBytes += size
+}
+
+// summary formats the totals, or returns "" if nothing was pulled
+func (s *pullStats) summary() string {
+	if s.downloaded == 0 && s.cached == 0 {
+		return ""
+	}
+	return fmt.Sprintf("✓ Downloaded %s in %s (%d %s, %d cache %s, %s from cache)",
+		formatBytes(s.downloadedBytes), formatDuration(time.Since(s.start)),
+		s.downloaded, plural(s.downloaded, "blob", "blobs"),
+		s.cached, plural(s.cached, "hit", "hits"),
+		formatBytes(s.cachedBytes))
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
 // spinnerSymbols for animated progress (ORAS style)
 var spinnerSymbols = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
 
@@ -43,6 +169,8 @@ type TextStatusHandler struct {
 	mu        sync.Mutex
 	startTime time.Time
 	progress  map[string]*NodeProgress
+	stats     pullStats
+	closeOnce sync.Once
 }
 
 // NewTextStatusHandler creates a text-based status handler
@@ -50,6 +178,7 @@ func NewTextStatusHandler() *TextStatusHandler {
 	return &TextStatusHandler{
 		startTime: time.Now(),
 		progress:  make(map[string]*NodeProgress),
+		stats:     pullStats{start: time.Now()},
 	}
 }
 
@@ -57,29 +186,26 @@ func (h *TextStatusHandler) OnNodeDownloading(desc ocispec.Descriptor) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	digestStr := desc.Digest.String()[:16]
-	h.progress[digestStr] = &NodeProgress{
+	h.progress[desc.Digest.String()] = &NodeProgress{
 		Descriptor:  desc,
 		Status:      "Downloading",
 		StartTime:   time.Now(),
 		DisplaySize: formatBytes(desc.Size),
 	}
 
-	fmt.Printf("↓ Pulling %s (%s)\n", digestStr, formatBytes(desc.Size))
+	fmt.Fprintf(messageOutput, "↓ Pulling %s (%s)\n", shortDigest(desc), formatBytes(desc.Size))
 }
 
 func (h *TextStatusHandler) OnNodeDownloaded(desc ocispec.Descriptor) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	digestStr := desc.Digest.String()[:16]
-	if p, ok := h.progress[digestStr]; ok {
+	if p, ok := h.progress[desc.Digest.String()]; ok {
 		p.Status = "Downloaded"
 		p.EndTime = time.Now()
 		p.BytesRead = desc.Size
-		duration := p.EndTime.Sub(p.StartTime)
-		speed := formatBytesPerSec(float64(desc.Size) / duration.Seconds())
-		fmt.Printf("✓ Pulled %s %s (%s/s)\n", digestStr, formatBytes(desc.Size), speed)
+		h.stats.addDownloaded(desc.Size)
+		fmt.Fprintf(messageOutput, "✓ Pulled %s %s (%s/s)\n", shortDigest(desc), formatBytes(desc.Size), formatBytesPerSec(p.Speed()))
 	}
 }
 
@@ -87,8 +213,7 @@ func (h *TextStatusHandler) OnNodeProcessing(desc ocispec.Descriptor) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	digestStr := desc.Digest.String()[:16]
-	if p, ok := h.progress[digestStr]; ok {
+	if p, ok := h.progress[desc.Digest.String()]; ok {
 		p.Status = "Processing"
 	}
 	// Don't show processing line - keep output minimal
@@ -98,24 +223,24 @@ func (h *TextStatusHandler) OnNodeRestored(desc ocispec.Descriptor) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	digestStr := desc.Digest.String()[:16]
-	if p, ok := h.progress[digestStr]; ok {
+	if p, ok := h.progress[desc.Digest.String()]; ok {
 		p.Status = "Restored"
 		p.EndTime = time.Now()
 	}
-	fmt.Printf("  └─ sha256:%s\n", desc.Digest.String()[7:])
+	fmt.Fprintf(messageOutput, "  └─ sha256:%s\n", desc.Digest.String()[7:])
 }
 
 func (h *TextStatusHandler) OnNodeSkipped(desc ocispec.Descriptor) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	digestStr := desc.Digest.String()[:16]
-	if p, ok := h.progress[digestStr]; ok {
+	if p, ok := h.progress[desc.Digest.String()]; ok {
 		p.Status = "Skipped"
 	}
-	fmt.Printf("  Skipped %s\n", digestStr)
+	h.stats.addCached(desc.Size)
+	fmt.Fprintf(messageOutput, "  Skipped %s (cached)\n", shortDigest(desc))
 }
+
 func (h *TextStatusHandler) UpdateProgress(digest string, bytesRead int64) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -124,21 +249,41 @@ func (h *TextStatusHandler) UpdateProgress(digest string, bytesRead int64) {
 		p.BytesRead = bytesRead
 	}
 }
+
+func (h *TextStatusHandler) Message(format string, args ...interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(messageOutput, format+"\n", args...)
+}
+
 func (h *TextStatusHandler) Close() {
-	// No cleanup needed for text handler
+	h.closeOnce.Do(func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if s := h.stats.summary(); s != "" {
+			fmt.Fprintln(messageOutput, s)
+		}
+	})
 }
 
 // TTYStatusHandler displays real-time progress with visual elements (TTY)
-// Uses ANSI codes for animated progress bars and status updates matching ORAS CLI
+// Each in-flight layer gets its own progress bar with speed and ETA. Status
+// lines are printed above the bars, which are redrawn in place using ANSI
+// cursor movement.
 type TTYStatusHandler struct {
 	mu         sync.Mutex
 	startTime  time.Time
 	progress   map[string]*NodeProgress
+	stats      pullStats
 	ticker     *time.Ticker
 	done       chan struct{}
 	wg         sync.WaitGroup
+	closeOnce  sync.Once
 	spinnerIdx int64
 	lastRender time.Time
+	// lines is the number of bar lines currently drawn below the cursor
+	lines int
 }
 
 // NewTTYStatusHandler creates a TTY-based status handler with real-time progress
@@ -146,7 +291,8 @@ func NewTTYStatusHandler() *TTYStatusHandler {
 	h := &TTYStatusHandler{
 		startTime: time.Now(),
 		progress:  make(map[string]*NodeProgress),
-		ticker:    time.NewTicker(100 * time.Millisecond), // 5 FPS like ORAS
+		stats:     pullStats{start: time.Now()},
+		ticker:    time.NewTicker(100 * time.Millisecond),
 		done:      make(chan struct{}),
 	}
 
@@ -174,52 +320,72 @@ func (h *TTYStatusHandler) render() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	// Find the best actively-downloading node to render
-	var p *NodeProgress
-	var bestSize int64
-	for _, np := range h.progress {
-		if np.Status == "Downloading" && np.Descriptor.Size > bestSize {
-			p = np
-			bestSize = np.Descriptor.Size
+	atomic.AddInt64(&h.spinnerIdx, 1)
+	h.clearBars()
+	h.drawBars()
+}
+
+// println prints a status line above the progress bars.
+// The caller must hold h.mu.
+func (h *TTYStatusHandler) println(format string, args ...interface{}) {
+	h.clearBars()
+	fmt.Fprintf(messageOutput, format+"\n", args...)
+	h.drawBars()
+}
+
+// clearBars erases the bars drawn by drawBars, leaving the cursor where the
+// first bar was
+func (h *TTYStatusHandler) clearBars() {
+	if h.lines > 0 {
+		fmt.Fprintf(messageOutput, "\x1b[%dA\x1b[J", h.lines)
+		h.lines = 0
+	}
+}
+
+// drawBars draws one line per in-flight layer, oldest first
+func (h *TTYStatusHandler) drawBars() {
+	var active []*NodeProgress
+	for _, p := range h.progress {
+		if p.Status == "Downloading" {
+			active = append(active, p)
 		}
 	}
-	if p == nil {
-		return
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].StartTime.Before(active[j].StartTime)
+	})
+
+	spinner := string(spinnerSymbols[int(atomic.LoadInt64(&h.spinnerIdx))%len(spinnerSymbols)])
+	for _, p := range active {
+		fmt.Fprintf(messageOutput, "  %s %s\n", spinner, progressLine(p))
 	}
+	h.lines = len(active)
+	h.lastRender = time.Now()
+}
 
-	// Generate spinner symbol
-	spinIdx := atomic.AddInt64(&h.spinnerIdx, 1)
-	spinnerChar := string(spinnerSymbols[int(spinIdx)%len(spinnerSymbols)])
+// progressLine renders the bar, byte count, speed and ETA of a layer
+func progressLine(p *NodeProgress) string {
+	digest := shortDigest(p.Descriptor)
+	if p.Descriptor.Size <= 0 {
+		// Indeterminate: show bytes so far
+		return fmt.Sprintf("%s downloading %s... %s", digest, formatBytes(p.BytesRead), formatDuration(time.Since(p.StartTime)))
+	}
 
-	// Calculate progress from BytesRead (if available) or show indeterminate
-	elapsed := time.Since(p.StartTime)
-	elapsedTime := formatDuration(elapsed)
+	ratio := float64(p.BytesRead) / float64(p.Descriptor.Size)
+	if ratio > 1.0 {
+		ratio = 1.0
+	}
+	const barLength = 20
+	filled := int(ratio * barLength)
+	bar := "[" + colorize(colorCyan, strings.Repeat("=", filled)) + strings.Repeat(" ", barLength-filled) + "]"
 
-	if p.BytesRead > 0 && p.Descriptor.Size > 0 {
-		progress := float64(p.BytesRead) / float64(p.Descriptor.Size)
-		if progress > 1.0 {
-			progress = 1.0
-		}
-		barLength := 20
-		filledLength := int(progress * float64(barLength))
-		emptyLength := barLength - filledLength
-		progressBar := "[" + strings.Repeat("=", filledLength) + strings.Repeat(" ", emptyLength) + "]"
-		speed := 0.0
-		if elapsed.Seconds() > 0 {
-			speed = float64(p.BytesRead) / elapsed.Seconds()
-		}
-		output := fmt.Sprintf("\r  %s %s %8s %s/%s %5.1f%% %8s",
-			spinnerChar, progressBar,
-			formatBytesPerSec(speed),
-			formatBytes(p.BytesRead), p.DisplaySize,
-			progress*100, elapsedTime)
-		fmt.Print(output)
-	} else {
-		// Indeterminate: show spinner with size being downloaded
-		output := fmt.Sprintf("\r  %s downloading %s... %8s",
-			spinnerChar, p.DisplaySize, elapsedTime)
-		fmt.Print(output)
+	eta := "--"
+	if d, ok := p.ETA(); ok {
+		eta = formatDuration(d)
 	}
+	return fmt.Sprintf("%s %s %5.1f%% %9s/%-9s %10s/s  ETA %s",
+		digest, bar, ratio*100,
+		formatBytes(p.BytesRead), p.DisplaySize,
+		formatBytesPerSec(p.Speed()), eta)
 }
 
 // formatDuration formats duration to human-readable format
@@ -258,32 +424,28 @@ func (h *TTYStatusHandler) OnNodeDownloading(desc ocispec.Descriptor) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	digestStr := desc.Digest.String()[:16]
-	h.progress[digestStr] = &NodeProgress{
+	now := time.Now()
+	h.progress[desc.Digest.String()] = &NodeProgress{
 		Descriptor:    desc,
 		Status:        "Downloading",
-		StartTime:     time.Now(),
+		StartTime:     now,
 		DisplaySize:   formatBytes(desc.Size),
-		LastSpeedTime: time.Now(),
-		LastSpeedRead: 0,
+		LastSpeedTime: now,
 	}
-
-	// Concise output like ORAS: just show activity
-	fmt.Printf("↓ Pulling %s (%s)\n", digestStr, formatBytes(desc.Size))
+	h.clearBars()
+	h.drawBars()
 }
 
 func (h *TTYStatusHandler) OnNodeDownloaded(desc ocispec.Descriptor) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	digestStr := desc.Digest.String()[:16]
-	if p, ok := h.progress[digestStr]; ok {
+	if p, ok := h.progress[desc.Digest.String()]; ok {
 		p.Status = "Downloaded"
 		p.EndTime = time.Now()
 		p.BytesRead = desc.Size
-		duration := p.EndTime.Sub(p.StartTime)
-		speed := formatBytesPerSec(float64(desc.Size) / duration.Seconds())
-		fmt.Printf("\r✓ Pulled %s %s (%s/s)\n", digestStr, formatBytes(desc.Size), speed)
+		h.stats.addDownloaded(desc.Size)
+		h.println("%s Pulled %s %s (%s/s)", colorize(colorGreen, "✓"), shortDigest(desc), formatBytes(desc.Size), formatBytesPerSec(p.Speed()))
 	}
 }
 
@@ -291,8 +453,7 @@ func (h *TTYStatusHandler) OnNodeProcessing(desc ocispec.Descriptor) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	digestStr := desc.Digest.String()[:16]
-	if p, ok := h.progress[digestStr]; ok {
+	if p, ok := h.progress[desc.Digest.String()]; ok {
 		p.Status = "Processing"
 	}
 	// Don't show processing line - keep output minimal
@@ -302,25 +463,23 @@ func (h *TTYStatusHandler) OnNodeRestored(desc ocispec.Descriptor) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	digestStr := desc.Digest.String()[:16]
-	if p, ok := h.progress[digestStr]; ok {
+	if p, ok := h.progress[desc.Digest.String()]; ok {
 		p.Status = "Restored"
 		p.EndTime = time.Now()
 		// Show digest on second line like ORAS
-		fmt.Printf("  └─ sha256:%s\n", desc.Digest.String()[7:])
+		h.println("  └─ sha256:%s", desc.Digest.String()[7:])
 	}
-	// Don't clear currentNode — render() will dynamically find the next active download
 }
 
 func (h *TTYStatusHandler) OnNodeSkipped(desc ocispec.Descriptor) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	digestStr := desc.Digest.String()[:16]
-	if p, ok := h.progress[digestStr]; ok {
+	if p, ok := h.progress[desc.Digest.String()]; ok {
 		p.Status = "Skipped"
 	}
-	fmt.Printf("\n  ⊘ Skipped %s\n", digestStr)
+	h.stats.addCached(desc.Size)
+	h.println("  %s", colorize(colorDim, "⊘ Skipped "+shortDigest(desc)+" (cached)"))
 }
 
 func (h *TTYStatusHandler) UpdateProgress(digest string, bytesRead int64) {
@@ -332,28 +491,122 @@ func (h *TTYStatusHandler) UpdateProgress(digest string, bytesRead int64) {
 	}
 }
 
+func (h *TTYStatusHandler) Message(format string, args ...interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.println(format, args...)
+}
+
 func (h *TTYStatusHandler) Close() {
-	h.ticker.Stop()
-	close(h.done)
-	h.wg.Wait()
-	// Final newline to move cursor to next line
-	fmt.Print("\n")
+	h.closeOnce.Do(func() {
+		h.ticker.Stop()
+		close(h.done)
+		h.wg.Wait()
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.clearBars()
+		if s := h.stats.summary(); s != "" {
+			fmt.Fprintln(messageOutput, s)
+		}
+	})
 }
 
-// NewStatusHandler creates appropriate handler based on TTY detection
+// currentHandler is the handler of the pull in progress, if any. It is
+// read from CopyGraph callbacks while pulls start and finish, so it is only
+// accessed atomically.
+var currentHandler atomic.Pointer[currentStatusHandler]
+
+// statusf prints a status message through the current handler, so it
+// doesn't corrupt a live progress display
+func statusf(format string, args ...interface{}) {
+	if h := currentHandler.Load(); h != nil {
+		h.Message(format, args...)
+		return
+	}
+	fmt.Fprintf(messageOutput, format+"\n", args...)
+}
+
+// Statusf prints a status message where progress messages go: stdout, or
+// stderr when stdout carries machine-readable output
+func Statusf(format string, args ...interface{}) {
+	statusf(format, args...)
+}
+
+// NewStatusHandler creates the handler for the configured progress mode and
+// makes it the current handler until it is closed.
+// In auto mode live bars are used only when messages go to a terminal.
 func NewStatusHandler() StatusHandler {
-	// Check if stdout is a TTY
-	if isTerminal(os.Stdout.Fd()) {
+	h := &currentStatusHandler{StatusHandler: newStatusHandler()}
+	currentHandler.Store(h)
+	return h
+}
+
+// currentStatusHandler stops being the current handler when closed
+type currentStatusHandler struct {
+	StatusHandler
+}
+
+func (h *currentStatusHandler) Close() {
+	currentHandler.CompareAndSwap(h, nil)
+	h.StatusHandler.Close()
+}
+
+func newStatusHandler() StatusHandler {
+	mode := progressMode
+	if mode == ProgressAuto {
+		mode = ProgressPlain
+		if isTerminal(messageOutput) && os.Getenv("TERM") != "dumb" {
+			mode = ProgressTTY
+		}
+	}
+
+	switch mode {
+	case ProgressTTY:
 		return NewTTYStatusHandler()
+	case ProgressJSON:
+		return NewJSONStatusHandler(messageOutput)
+	case ProgressNone:
+		return &nopStatusHandler{}
 	}
 	return NewTextStatusHandler()
 }
 
-// isTerminal checks if a file descriptor is connected to a terminal
-func isTerminal(fd uintptr) bool {
-	// Simple check: if it's stdout/stderr and not piped
-	// On Unix-like systems, we could use tcgetattr but this is simpler
-	return fd == 1 || fd == 2 // stdout or stderr
+// isTerminal reports whether w is a file connected to a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// ANSI colors used by the TTY handler
+const (
+	colorGreen = "32"
+	colorCyan  = "36"
+	colorDim   = "2"
+)
+
+// colorize wraps s in an ANSI color unless NO_COLOR is set
+func colorize(color, s string) string {
+	if os.Getenv("NO_COLOR") != "" || s == "" {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+// nopStatusHandler shows no progress, only status messages
+type nopStatusHandler struct{}
+
+func (nopStatusHandler) OnNodeDownloading(ocispec.Descriptor) {}
+func (nopStatusHandler) OnNodeDownloaded(ocispec.Descriptor)  {}
+func (nopStatusHandler) OnNodeProcessing(ocispec.Descriptor)  {}
+func (nopStatusHandler) OnNodeRestored(ocispec.Descriptor)    {}
+func (nopStatusHandler) OnNodeSkipped(ocispec.Descriptor)     {}
+func (nopStatusHandler) UpdateProgress(string, int64)         {}
+func (nopStatusHandler) Close()                               {}
+
+func (nopStatusHandler) Message(format string, args ...interface{}) {
+	fmt.Fprintf(messageOutput, format+"\n", args...)
 }
 
 // formatBytes formats bytes into human-readable format (B, KB, MB, GB)
@@ -0,0 +1,37 @@
+package runtime
+
+import (
+	"io"
+	"os"
+	"sync"
+	"testing"
+)
+
+// Status messages from CopyGraph callbacks race with pulls starting and
+// finishing; run with -race
+func TestStatusfConcurrentWithHandlers(t *testing.T) {
+	SetMessageOutput(io.Discard)
+	defer SetMessageOutput(os.Stdout)
+	progressMode = ProgressNone
+	defer func() { progressMode = ProgressAuto }()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			NewStatusHandler().Close()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			statusf("message %d", i)
+		}
+	}()
+	wg.Wait()
+
+	if h := currentHandler.Load(); h != nil {
+		t.Errorf("current handler still set after every handler was closed")
+	}
+}
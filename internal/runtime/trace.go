@@ -0,0 +1,143 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing is configured through the environment:
+//
+//	THIN_TRACE=otlp   export spans over OTLP/HTTP (honours OTEL_EXPORTER_OTLP_* variables)
+//	THIN_TRACE=file   write spans as JSON to THIN_TRACE_FILE (default: thin-trace.json)
+//
+// When THIN_TRACE is unset, spans are created against a no-op tracer.
+const tracerName = "github.com/sourceplane/thin"
+
+var tracePropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// InitTracing installs the configured span exporter and returns a context
+// carrying any parent trace passed in through TRACEPARENT, along with a
+// shutdown function that flushes pending spans.
+func InitTracing(ctx context.Context, version string) (context.Context, func()) {
+	otel.SetTextMapPropagator(tracePropagator)
+
+	// Nest under the caller's trace when thin is itself run by a provider
+	ctx = tracePropagator.Extract(ctx, envCarrier())
+
+	exporter, closeOutput, err := newSpanExporter(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠ Warning: tracing disabled: %v\n", err)
+		return ctx, func() {}
+	}
+	if exporter == nil {
+		return ctx, func() {}
+	}
+
+	res, _ := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("thin"),
+		semconv.ServiceVersion(version),
+	))
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return ctx, func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠ Warning: failed to flush traces: %v\n", err)
+		}
+		if closeOutput != nil {
+			if err := closeOutput(); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠ Warning: failed to write traces: %v\n", err)
+			}
+		}
+	}
+}
+
+// newSpanExporter builds the exporter selected by THIN_TRACE, along with a
+// function that closes its output once the exporter is shut down (nil if
+// there is nothing to close)
+// Returns nil if tracing is not enabled
+func newSpanExporter(ctx context.Context) (sdktrace.SpanExporter, func() error, error) {
+	switch mode := strings.ToLower(os.Getenv("THIN_TRACE")); mode {
+	case "", "off", "none":
+		return nil, nil, nil
+	case "otlp":
+		exporter, err := otlptracehttp.New(ctx)
+		return exporter, nil, err
+	case "file", "json":
+		path := os.Getenv("THIN_TRACE_FILE")
+		if path == "" {
+			path = "thin-trace.json"
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exporter, f.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown THIN_TRACE exporter %q (expected: otlp or file)", mode)
+	}
+}
+
+// StartSpan starts a span under the thin tracer
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records err on span (if any) and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceEnv returns TRACEPARENT/TRACESTATE entries for the span in ctx,
+// so that provider processes can parent their spans under thin's
+func TraceEnv(ctx context.Context) []string {
+	carrier := propagation.MapCarrier{}
+	tracePropagator.Inject(ctx, carrier)
+
+	var env []string
+	for key, value := range carrier {
+		env = append(env, strings.ToUpper(key)+"="+value)
+	}
+	return env
+}
+
+// envCarrier exposes TRACEPARENT/TRACESTATE/BAGGAGE from the environment
+func envCarrier() propagation.MapCarrier {
+	carrier := propagation.MapCarrier{}
+	for _, key := range []string{"traceparent", "tracestate", "baggage"} {
+		if v := os.Getenv(strings.ToUpper(key)); v != "" {
+			carrier[key] = v
+		}
+	}
+	return carrier
+}
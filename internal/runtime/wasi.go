@@ -0,0 +1,220 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// WASIExitError is returned when a wasi module exits with a non-zero code
+type WASIExitError struct {
+	Code uint32
+}
+
+func (e *WASIExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExecWASI runs a wasi provider module in the embedded runtime.
+//
+// argv[0] is the module's file name, followed by args. The module sees only
+// the variables named in cfg.Env plus env (thin's own THIN_* and trace
+// context); stdio is the host's. Directories in cfg.Preopens are mounted into
+// the module's filesystem, defaulting to the working directory at "/"; all
+// of them are read-only unless declared writable.
+// ExecOptions in ctx set that directory, extra variables and a timeout.
+func ExecWASI(ctx context.Context, modulePath string, args []string, cfg WASIRuntime, env ...string) (err error) {
+	ctx, span := StartSpan(ctx, "thin.exec",
+		attribute.String("thin.exec.runtime", RuntimeWASI),
+		attribute.String("thin.exec.path", modulePath),
+		attribute.Int("thin.exec.args.count", len(args)),
+	)
+	defer func() { EndSpan(span, err) }()
+
+	opts := ExecOptionsFrom(ctx)
+	ctx, cancel := opts.withTimeout(ctx)
+	defer cancel()
+
+	wasm, err := os.ReadFile(modulePath)
+	if err != nil {
+		return fmt.Errorf("failed to read wasm module: %w", err)
+	}
+
+	// Compiled modules are cached so repeat runs skip compilation
+	runtimeConfig := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if cache, err := wazero.NewCompilationCacheWithDir(filepath.Join(CacheHome(), "wazero")); err == nil {
+		runtimeConfig = runtimeConfig.WithCompilationCache(cache)
+	}
+
+	r := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	defer r.Close(ctx)
+
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		return fmt.Errorf("failed to compile wasm module: %w", err)
+	}
+
+	fsConfig, err := wasiFSConfig(cfg.Preopens, opts.Dir)
+	if err != nil {
+		return err
+	}
+
+	// The runtime closes the module when ctx is done, but only notices
+	// between calls: sleeping and reading stdin, where a module can block
+	// for good, return early instead so the module gets to that point
+	moduleConfig := wazero.NewModuleConfig().
+		WithArgs(append([]string{filepath.Base(modulePath)}, args...)...).
+		WithStdin(&contextReader{ctx: ctx, r: os.Stdin}).
+		WithStdout(os.Stdout).
+		WithStderr(os.Stderr).
+		WithFSConfig(fsConfig).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithNanosleep(func(ns int64) {
+			timer := time.NewTimer(time.Duration(ns))
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+			}
+		}).
+		WithRandSource(rand.Reader)
+
+	for _, kv := range wasiEnv(cfg.Env, ExecEnv(ctx, env...)) {
+		key, value, _ := strings.Cut(kv, "=")
+		moduleConfig = moduleConfig.WithEnv(key, value)
+	}
+
+	_, err = r.InstantiateModule(ctx, compiled, moduleConfig)
+	if ctx.Err() != nil {
+		return opts.contextError(ctx)
+	}
+	if err != nil {
+		var exitErr *sys.ExitError
+		if errors.As(err, &exitErr) {
+			span.SetAttributes(attribute.Int("thin.exec.exit_code", int(exitErr.ExitCode())))
+			if exitErr.ExitCode() == 0 {
+				return nil
+			}
+			return &WASIExitError{Code: exitErr.ExitCode()}
+		}
+		return fmt.Errorf("wasm module failed: %w", err)
+	}
+	span.SetAttributes(attribute.Int("thin.exec.exit_code", 0))
+	return nil
+}
+
+// wasiFSConfig mounts the preopened directories, read-only unless the
+// manifest marks them writable. Relative host paths are resolved against
+// dir, or the working directory when dir is empty; without preopens dir
+// itself is mounted read-only at "/".
+func wasiFSConfig(preopens []WASIPreopen, dir string) (wazero.FSConfig, error) {
+	fsConfig := wazero.NewFSConfig()
+
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		dir = wd
+	}
+	if len(preopens) == 0 {
+		return fsConfig.WithReadOnlyDirMount(dir, "/"), nil
+	}
+
+	for _, p := range preopens {
+		host := p.Host
+		if !filepath.IsAbs(host) {
+			host = filepath.Join(dir, host)
+		}
+		host, err := filepath.Abs(host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preopen %s: %w", p.Host, err)
+		}
+		if stat, err := os.Stat(host); err != nil || !stat.IsDir() {
+			return nil, fmt.Errorf("preopen directory not found: %s", host)
+		}
+		guest := p.Guest
+		if guest == "" {
+			guest = host
+		}
+		if p.Writable {
+			fsConfig = fsConfig.WithDirMount(host, guest)
+		} else {
+			fsConfig = fsConfig.WithReadOnlyDirMount(host, guest)
+		}
+	}
+	return fsConfig, nil
+}
+
+// wasiEnv builds the module environment from the manifest's env entries
+// (NAME passes the host value through, NAME=value sets it) and thin's own
+// entries, which take precedence
+func wasiEnv(manifestEnv []string, extra []string) []string {
+	var entries []string
+	for _, entry := range manifestEnv {
+		if strings.Contains(entry, "=") {
+			entries = append(entries, entry)
+		} else if value, ok := os.LookupEnv(entry); ok {
+			entries = append(entries, entry+"="+value)
+		}
+	}
+	entries = append(entries, extra...)
+
+	// Later entries win, keeping the position of the first occurrence
+	var env []string
+	index := map[string]int{}
+	for _, kv := range entries {
+		key, _, _ := strings.Cut(kv, "=")
+		if i, ok := index[key]; ok {
+			env[i] = kv
+			continue
+		}
+		index[key] = len(env)
+		env = append(env, kv)
+	}
+	return env
+}
+
+// contextReader reads from r until ctx is done. A Read still blocked in r
+// then returns ctx's error; the underlying read is left to finish in the
+// background and its data is dropped.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	type result struct {
+		n   int
+		err error
+	}
+	buf := make([]byte, len(p))
+	done := make(chan result, 1)
+	go func() {
+		n, err := c.r.Read(buf)
+		done <- result{n, err}
+	}()
+	select {
+	case res := <-done:
+		return copy(p, buf[:res.n]), res.err
+	case <-c.ctx.Done():
+		return 0, c.ctx.Err()
+	}
+}
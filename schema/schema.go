@@ -0,0 +1,32 @@
+// Package schema embeds the published JSON Schemas for thin.provider.yaml
+package schema
+
+import _ "embed"
+
+// ProviderManifest is the JSON Schema for thin.io/v1 manifests
+//
+//go:embed thin.provider.schema.json
+var ProviderManifest []byte
+
+// ProviderManifestV2 is the JSON Schema for thin.io/v2 manifests
+//
+//go:embed thin.provider.v2.schema.json
+var ProviderManifestV2 []byte
+
+// Where the schemas are published, for use with
+// "# yaml-language-server: $schema=<url>" editor hints
+const (
+	ProviderManifestURL   = "https://raw.githubusercontent.com/sourceplane/thin/main/schema/thin.provider.schema.json"
+	ProviderManifestV2URL = "https://raw.githubusercontent.com/sourceplane/thin/main/schema/thin.provider.v2.schema.json"
+)
+
+// ForAPIVersion returns the schema for a manifest apiVersion
+func ForAPIVersion(apiVersion string) ([]byte, bool) {
+	switch apiVersion {
+	case "thin.io/v1":
+		return ProviderManifest, true
+	case "thin.io/v2":
+		return ProviderManifestV2, true
+	}
+	return nil, false
+}